@@ -1,8 +1,6 @@
 package cmd
 
 import (
-	"fmt"
-
 	"github.com/spf13/cobra"
 )
 
@@ -12,8 +10,14 @@ var checkCmd = &cobra.Command{
 	RunE:  runCheckCmd,
 }
 
+var failOn string
+var outputFormat string
+
 func init() {
 	rootCmd.AddCommand(checkCmd)
+	addRuleFlags(checkCmd)
+	checkCmd.Flags().StringVarP(&outputFormat, "output", "o", outputText, "Output format of the report (text, json or sarif)")
+	checkCmd.Flags().StringVar(&failOn, "fail-on", "info", "Minimum severity of violations that makes the check fail (info, warning, error or none)")
 }
 
 func runCheckCmd(cmd *cobra.Command, args []string) error {
@@ -22,87 +26,62 @@ func runCheckCmd(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	tfFiles, err := getTerraformFiles()
-	if err != nil {
+	if err = applyConfig(cmd); err != nil {
 		return err
 	}
 
-	err = checkUnneededAttributeAssignments(tfFiles)
+	tfFiles, err := getTerraformFiles()
 	if err != nil {
 		return err
 	}
 
-	err = checkFormatUsages(tfFiles)
-	if err != nil {
+	if err = validateOutputFormat(outputFormat); err != nil {
 		return err
 	}
 
-	return nil
-}
-
-// checks for attribute assignments with default values of variable from their module
-func checkUnneededAttributeAssignments(tfFiles []string) error {
-	report, err := checkForUnneededAttributeAssignments(tfFiles)
+	rules, err := getSelectedRules()
 	if err != nil {
 		return err
 	}
 
-	if len(report) == 0 {
-		fmt.Println("No unneeded module assignments were found")
-		return nil
+	failOnAny := failOn != "none"
+	var threshold severity
+	if failOnAny {
+		threshold, err = parseSeverity(failOn)
+		if err != nil {
+			return err
+		}
 	}
 
-	fmt.Println("== RESULTS FOR THE UNNEEDED MODULE ASSIGNMENTS ==")
-
-	for mod, unneededAssigns := range report {
-		if len(unneededAssigns) == 0 {
-			fmt.Printf("\n\tNo unneeded module assignments were found for module '%v'\n", mod.name())
-			continue
+	failed := false
+	var results []ruleResult
+	for _, r := range rules {
+		violations, err := r.check(tfFiles)
+		if err != nil {
+			return err
 		}
 
-		fmt.Printf("\n\tThe following module assignments are unneeded for module '%v':\n", mod.name())
+		sortViolations(violations)
+		results = append(results, ruleResult{r, violations})
 
-		for _, assign := range unneededAssigns {
-			fmt.Printf("\t\t%v", assign.name())
-			if verbose {
-				fmt.Printf(" (%v)", assign.location())
-			}
-			fmt.Println()
+		if failOnAny && len(violations) > 0 && r.severity >= threshold {
+			failed = true
 		}
 	}
 
-	return nil
-}
-
-// check for format() usage
-func checkFormatUsages(tfFiles []string) error {
-	report, err := checkForFormatUsage(tfFiles)
-	if err != nil {
+	if err = writeReport(outputFormat, results); err != nil {
 		return err
 	}
 
-	if len(report) == 0 {
-		fmt.Println("No format() usages were found")
-		return nil
+	// a skipped file might contain violations as well, so that fails the check harder than the violations found
+	if err = skippedFilesError(cmd); err != nil {
+		return err
 	}
 
-	fmt.Println("== RESULTS FOR FORMAT() USAGES ==")
-
-	for filename, formatInvocations := range report {
-		if len(formatInvocations) == 0 {
-			fmt.Printf("\n\tNo format() usages were found for file '%v'\n", filename)
-			continue
-		}
-
-		fmt.Printf("\n\tThe following format() usages were found for file '%v':\n", filename)
-
-		for _, invoke := range formatInvocations {
-			fmt.Printf("\t\t%v", invoke.string())
-			if verbose {
-				fmt.Printf(" (%v)", invoke.location())
-			}
-			fmt.Println()
-		}
+	if failed {
+		cmd.SilenceErrors = true
+		cmd.SilenceUsage = true
+		return errViolationsFound
 	}
 
 	return nil
@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/spf13/cobra"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const configFilename = ".tfcleanup.hcl"
+
+// config is the project configuration, read from .tfcleanup.hcl, where the CLI flags override the settings
+type config struct {
+	Output    *string      `hcl:"output,optional"`
+	FailOn    *string      `hcl:"fail_on,optional"`
+	Recursive *bool        `hcl:"recursive,optional"`
+	Exclude   []string     `hcl:"exclude,optional"`
+	VarFiles  []string     `hcl:"var_files,optional"`
+	Enable    []string     `hcl:"enable,optional"`
+	Disable   []string     `hcl:"disable,optional"`
+	Rules     []ruleConfig `hcl:"rule,block"`
+}
+
+type ruleConfig struct {
+	Id       string   `hcl:"id,label"`
+	Enabled  *bool    `hcl:"enabled,optional"`
+	Severity *string  `hcl:"severity,optional"`
+	Options  hcl.Body `hcl:",remain"`
+}
+
+var configFile string
+
+// ruleOptions holds the rule specific options from the config, per rule ID
+var ruleOptions = make(map[string]map[string]cty.Value)
+
+// applyConfig reads the config file, if any, and applies its settings for the flags that are not set explicitly
+func applyConfig(cmd *cobra.Command) error {
+	filename := configFile
+	if filename == "" {
+		var err error
+		filename, err = findConfigFile()
+		if err != nil || filename == "" {
+			return err
+		}
+	}
+
+	cfg, err := readConfig(filename)
+	if err != nil {
+		return err
+	}
+
+	if verbose {
+		fmt.Fprintf(os.Stderr, "Using config file: %v\n", filename)
+	}
+
+	if cfg.Output != nil {
+		setDefaultFlag(cmd, "output", *cfg.Output)
+	}
+	if cfg.FailOn != nil {
+		setDefaultFlag(cmd, "fail-on", *cfg.FailOn)
+	}
+	if cfg.Recursive != nil {
+		setDefaultFlag(cmd, "recursive", fmt.Sprint(*cfg.Recursive))
+	}
+	if len(cfg.Exclude) > 0 {
+		setDefaultFlag(cmd, "exclude", strings.Join(cfg.Exclude, ","))
+	}
+	if len(cfg.VarFiles) > 0 {
+		setDefaultFlag(cmd, "var-file", strings.Join(cfg.VarFiles, ","))
+	}
+
+	disabled := cfg.Disable
+	for _, rc := range cfg.Rules {
+		r, exists := getRule(rc.Id)
+		if !exists {
+			return fmt.Errorf("%v: unknown rule '%v' (known rules: %v)", filename, rc.Id, strings.Join(ruleIds(), ", "))
+		}
+
+		if rc.Enabled != nil && !*rc.Enabled {
+			disabled = append(disabled, rc.Id)
+		}
+
+		if rc.Severity != nil {
+			sev, err := parseSeverity(*rc.Severity)
+			if err != nil {
+				return fmt.Errorf("%v: rule '%v': %s", filename, rc.Id, err)
+			}
+			setRuleSeverity(rc.Id, sev)
+		}
+
+		options, err := decodeRuleOptions(r, rc.Options)
+		if err != nil {
+			return fmt.Errorf("%v: rule '%v': %s", filename, rc.Id, err)
+		}
+		ruleOptions[rc.Id] = options
+	}
+
+	// when the rules are enabled explicitly on the command line, the config doesn't apply anymore
+	if !isFlagChanged(cmd, "enable") {
+		enabledRules = cfg.Enable
+		disabledRules = append(disabledRules, disabled...)
+	}
+
+	return nil
+}
+
+// findConfigFile looks for the config file in the working dir, and its parent dirs
+func findConfigFile() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		filename := filepath.Join(dir, configFilename)
+		if _, err := os.Stat(filename); err == nil {
+			return filename, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+func readConfig(filename string) (*config, error) {
+	hclFile, diags := hclparse.NewParser().ParseHCLFile(filename)
+	if diags.HasErrors() {
+		return nil, errors.New("failed to parse config file: " + diags.Error())
+	}
+
+	var cfg config
+	if diags = gohcl.DecodeBody(hclFile.Body, nil, &cfg); diags.HasErrors() {
+		return nil, errors.New("failed to parse config file: " + diags.Error())
+	}
+
+	return &cfg, nil
+}
+
+func decodeRuleOptions(r rule, body hcl.Body) (map[string]cty.Value, error) {
+	attrs, diags := body.JustAttributes()
+	if diags.HasErrors() {
+		return nil, errors.New(diags.Error())
+	}
+
+	options := make(map[string]cty.Value)
+	for name, attr := range attrs {
+		if !contains(r.options, name) {
+			return nil, fmt.Errorf("unknown option '%v'", name)
+		}
+
+		val, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() {
+			return nil, errors.New(diags.Error())
+		}
+		options[name] = val
+	}
+
+	return options, nil
+}
+
+// getRuleOption returns the value of the option of the rule from the config, if set
+func getRuleOption(ruleId, name string) (cty.Value, bool) {
+	val, exists := ruleOptions[ruleId][name]
+	return val, exists
+}
+
+func setRuleSeverity(id string, sev severity) {
+	for i := range registeredRules {
+		if registeredRules[i].id == id {
+			registeredRules[i].severity = sev
+		}
+	}
+}
+
+func isFlagChanged(cmd *cobra.Command, name string) bool {
+	flag := cmd.Flags().Lookup(name)
+	return flag != nil && flag.Changed
+}
+
+// setDefaultFlag sets the flag to the value from the config, unless the flag is set on the command line
+func setDefaultFlag(cmd *cobra.Command, name, value string) {
+	if cmd.Flags().Lookup(name) == nil || isFlagChanged(cmd, name) {
+		return
+	}
+	cmd.Flags().Set(name, value)
+}
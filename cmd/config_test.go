@@ -0,0 +1,241 @@
+package cmd
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestFindConfigFile(t *testing.T) {
+	testCases := []struct {
+		name     string
+		files    []string
+		workDir  string
+		expected string
+	}{
+		{name: "in working dir", files: []string{configFilename}, workDir: ".", expected: configFilename},
+		{name: "in parent dir", files: []string{configFilename}, workDir: "a/b", expected: configFilename},
+		{name: "nearest wins", files: []string{configFilename, "a/" + configFilename}, workDir: "a/b", expected: "a/" + configFilename},
+		{name: "not in child dir", files: []string{"a/b/" + configFilename}, workDir: "a", expected: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir, err := filepath.EvalSymlinks(t.TempDir())
+			if err != nil {
+				t.Fatalf("failed to resolve temp dir: %v", err)
+			}
+			files := map[string]string{"a/b/main.tf": ""}
+			for _, f := range tc.files {
+				files[f] = ""
+			}
+			writeTestFiles(t, dir, files)
+			chdirForTest(t, filepath.Join(dir, tc.workDir))
+
+			result, err := findConfigFile()
+			if err != nil {
+				t.Fatalf("findConfigFile() error = %v", err)
+			}
+
+			expected := ""
+			if tc.expected != "" {
+				expected = filepath.Join(dir, tc.expected)
+			}
+			// a config file outside of the temp dir is not part of the test
+			if tc.expected == "" && result != "" && !strings.HasPrefix(result, dir) {
+				result = ""
+			}
+			if result != expected {
+				t.Errorf("findConfigFile() = '%s'; want '%s'", result, expected)
+			}
+		})
+	}
+}
+
+// newConfigTestCommand returns a command with the flags that the config applies to, parsed from the args
+func newConfigTestCommand(t *testing.T, args []string) *cobra.Command {
+	severities := make(map[string]severity)
+	for _, r := range registeredRules {
+		severities[r.id] = r.severity
+	}
+	t.Cleanup(func() {
+		for id, sev := range severities {
+			setRuleSeverity(id, sev)
+		}
+		ruleOptions = make(map[string]map[string]cty.Value)
+		configFile = ""
+	})
+
+	cmd := &cobra.Command{}
+	addRuleFlags(cmd)
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", outputText, "")
+	cmd.Flags().StringVar(&failOn, "fail-on", "info", "")
+	cmd.Flags().BoolVarP(&recursive, "recursive", "R", false, "")
+	cmd.Flags().StringSliceVar(&excludePatterns, "exclude", nil, "")
+	cmd.Flags().StringSliceVar(&varFiles, "var-file", nil, "")
+	if err := cmd.ParseFlags(args); err != nil {
+		t.Fatalf("failed to parse flags %v: %v", args, err)
+	}
+	return cmd
+}
+
+func TestApplyConfigPrecedence(t *testing.T) {
+	config := `output    = "json"
+fail_on   = "warning"
+recursive = true
+exclude   = ["legacy/**"]
+enable    = ["format-usage", "join-usage", "unused-local"]
+disable   = ["join-usage"]
+
+rule "unused-local" {
+  enabled = false
+}
+`
+	testCases := []struct {
+		name              string
+		config            string
+		args              []string
+		expectedOutput    string
+		expectedFailOn    string
+		expectedRecursive bool
+		expectedExcludes  []string
+		expectedEnabled   []string
+		expectedDisabled  []string
+	}{
+		{
+			name:           "defaults without config",
+			expectedOutput: outputText,
+			expectedFailOn: "info",
+		},
+		{
+			name:              "config",
+			config:            config,
+			expectedOutput:    "json",
+			expectedFailOn:    "warning",
+			expectedRecursive: true,
+			expectedExcludes:  []string{"legacy/**"},
+			expectedEnabled:   []string{"format-usage", "join-usage", "unused-local"},
+			expectedDisabled:  []string{"join-usage", "unused-local"},
+		},
+		{
+			name:              "flags override config",
+			config:            config,
+			args:              []string{"--output", "sarif", "--fail-on", "error", "--recursive=false", "--exclude", "other/**"},
+			expectedOutput:    "sarif",
+			expectedFailOn:    "error",
+			expectedRecursive: false,
+			expectedExcludes:  []string{"other/**"},
+			expectedEnabled:   []string{"format-usage", "join-usage", "unused-local"},
+			expectedDisabled:  []string{"join-usage", "unused-local"},
+		},
+		{
+			name:              "enable flag replaces the rule selection of the config",
+			config:            config,
+			args:              []string{"--enable", "join-usage"},
+			expectedOutput:    "json",
+			expectedFailOn:    "warning",
+			expectedRecursive: true,
+			expectedExcludes:  []string{"legacy/**"},
+			expectedEnabled:   []string{"join-usage"},
+		},
+		{
+			name:              "disable flag is merged with the config",
+			config:            config,
+			args:              []string{"--disable", "format-usage"},
+			expectedOutput:    "json",
+			expectedFailOn:    "warning",
+			expectedRecursive: true,
+			expectedExcludes:  []string{"legacy/**"},
+			expectedEnabled:   []string{"format-usage", "join-usage", "unused-local"},
+			expectedDisabled:  []string{"format-usage", "join-usage", "unused-local"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tc.config != "" {
+				writeTestFiles(t, dir, map[string]string{configFilename: tc.config})
+				configFile = filepath.Join(dir, configFilename)
+			} else {
+				// an empty config file, so a config file in the parent dirs of the temp dir doesn't apply
+				writeTestFiles(t, dir, map[string]string{configFilename: ""})
+			}
+			chdirForTest(t, dir)
+
+			cmd := newConfigTestCommand(t, tc.args)
+			if err := applyConfig(cmd); err != nil {
+				t.Fatalf("applyConfig() error = %v", err)
+			}
+
+			if outputFormat != tc.expectedOutput {
+				t.Errorf("output = %s; want %s", outputFormat, tc.expectedOutput)
+			}
+			if failOn != tc.expectedFailOn {
+				t.Errorf("fail-on = %s; want %s", failOn, tc.expectedFailOn)
+			}
+			if recursive != tc.expectedRecursive {
+				t.Errorf("recursive = %t; want %t", recursive, tc.expectedRecursive)
+			}
+			if len(excludePatterns) > 0 || len(tc.expectedExcludes) > 0 {
+				if !reflect.DeepEqual(excludePatterns, tc.expectedExcludes) {
+					t.Errorf("exclude = %v; want %v", excludePatterns, tc.expectedExcludes)
+				}
+			}
+			if len(enabledRules) > 0 || len(tc.expectedEnabled) > 0 {
+				if !reflect.DeepEqual(enabledRules, tc.expectedEnabled) {
+					t.Errorf("enabled rules = %v; want %v", enabledRules, tc.expectedEnabled)
+				}
+			}
+			if len(disabledRules) > 0 || len(tc.expectedDisabled) > 0 {
+				if !reflect.DeepEqual(disabledRules, tc.expectedDisabled) {
+					t.Errorf("disabled rules = %v; want %v", disabledRules, tc.expectedDisabled)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyConfigRuleSettings(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{configFilename: `rule "format-usage" {
+  severity = "error"
+}
+
+rule "join-usage" {
+  separators = ["-"]
+}
+`})
+	chdirForTest(t, dir)
+
+	cmd := newConfigTestCommand(t, nil)
+	if err := applyConfig(cmd); err != nil {
+		t.Fatalf("applyConfig() error = %v", err)
+	}
+
+	if r, _ := getRule("format-usage"); r.severity != severityError {
+		t.Errorf("severity of format-usage = %v; want error", r.severity)
+	}
+	separators, err := getJoinSeparators()
+	if err != nil {
+		t.Fatalf("getJoinSeparators() error = %v", err)
+	}
+	if !reflect.DeepEqual(separators, []string{"-"}) {
+		t.Errorf("join-usage separators = %v; want [-]", separators)
+	}
+}
+
+func TestApplyConfigUnknownRule(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{configFilename: "rule \"no-such-rule\" {}\n"})
+	chdirForTest(t, dir)
+
+	cmd := newConfigTestCommand(t, nil)
+	if err := applyConfig(cmd); err == nil {
+		t.Errorf("applyConfig() succeeded; want an error for the unknown rule")
+	}
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// diagnosticsOutput is where the diagnostics are printed, which tests replace to check them
+var diagnosticsOutput io.Writer = os.Stderr
+
+// reportDiagnostic prints the diagnostic on stderr, so it doesn't end up in the report on stdout
+func reportDiagnostic(diag *hcl.Diagnostic) {
+	label := "WARNING"
+	if diag.Severity == hcl.DiagError {
+		label = "ERROR"
+	}
+
+	fmt.Fprintf(diagnosticsOutput, "%v: ", label)
+	if diag.Subject != nil {
+		fmt.Fprintf(diagnosticsOutput, "%v: ", location(*diag.Subject))
+	}
+	fmt.Fprint(diagnosticsOutput, diag.Summary)
+	if diag.Detail != "" {
+		fmt.Fprintf(diagnosticsOutput, "; %v", diag.Detail)
+	}
+	fmt.Fprintln(diagnosticsOutput)
+}
+
+func reportWarning(subject *hcl.Range, summary, detail string) {
+	reportDiagnostic(&hcl.Diagnostic{
+		Severity: hcl.DiagWarning,
+		Summary:  summary,
+		Detail:   detail,
+		Subject:  subject,
+	})
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	testCases := []struct {
+		name     string
+		old      string
+		new      string
+		expected string
+	}{
+		{
+			name:     "no changes",
+			old:      "a\nb\n",
+			new:      "a\nb\n",
+			expected: "",
+		},
+		{
+			name: "single line changed",
+			old:  "a\nb\nc\n",
+			new:  "a\nB\nc\n",
+			expected: `--- a/main.tf
++++ b/main.tf
+@@ -1,3 +1,3 @@
+ a
+-b
++B
+ c
+`,
+		},
+		{
+			name: "line removed, with limited context",
+			old:  "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			new:  "1\n2\n3\n4\n6\n7\n8\n9\n",
+			expected: `--- a/main.tf
++++ b/main.tf
+@@ -2,7 +2,6 @@
+ 2
+ 3
+ 4
+-5
+ 6
+ 7
+ 8
+`,
+		},
+		{
+			name: "distant changes, in separate hunks",
+			old:  "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			new:  "0\n2\n3\n4\n5\n6\n7\n8\n9\n11\n",
+			expected: `--- a/main.tf
++++ b/main.tf
+@@ -1,4 +1,4 @@
+-1
++0
+ 2
+ 3
+ 4
+@@ -7,4 +7,4 @@
+ 7
+ 8
+ 9
+-10
++11
+`,
+		},
+		{
+			name: "added to empty file",
+			old:  "",
+			new:  "a\n",
+			expected: `--- a/main.tf
++++ b/main.tf
+@@ -0,0 +1 @@
++a
+`,
+		},
+		{
+			name: "missing newline at end of file",
+			old:  "a\nb",
+			new:  "a\n",
+			expected: `--- a/main.tf
++++ b/main.tf
+@@ -1,2 +1 @@
+ a
+-b
+\ No newline at end of file
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := unifiedDiff("main.tf", []byte(tc.old), []byte(tc.new))
+			if err != nil {
+				t.Fatalf("unifiedDiff(%q, %q) error = %v", tc.old, tc.new, err)
+			}
+			if result != tc.expected {
+				t.Errorf("unifiedDiff(%q, %q) = \n%s\nwant\n%s", tc.old, tc.new, result, tc.expected)
+			}
+		})
+	}
+}
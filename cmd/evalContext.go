@@ -0,0 +1,203 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// varFiles are the .tfvars files that set the variables of the root module in the target dir
+var varFiles []string
+
+// moduleEvalContexts caches the evaluation context per module dir
+var moduleEvalContexts = make(map[string]*hcl.EvalContext)
+
+// getModuleEvalContext returns the context to evaluate the expressions in the module dir with, which holds the values
+// of the variables and locals, as far as they are known before running terraform. Only the variables that are set by
+// the .tfvars files of a root module are known, as the defaults can be overridden with -var or TF_VAR_ environment
+// variables, and the variables of other modules are set by their callers, which might not be part of the checked files.
+func getModuleEvalContext(dir string, moduleCalls []module) (*hcl.EvalContext, error) {
+	dir = path.Clean(dir)
+	if ctx, exists := moduleEvalContexts[dir]; exists {
+		return ctx, nil
+	}
+
+	filenames, err := getDirTerraformFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	isRootModule, err := isRootModuleDir(dir, moduleCalls)
+	if err != nil {
+		return nil, err
+	}
+
+	declared := make(map[string]variableDefinition)
+	variables := make(map[string]cty.Value)
+	localExprs := make(map[string]hclsyntax.Expression)
+	for _, f := range filenames {
+		vars, err := readVariables(f)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range vars {
+			declared[v.name()] = v
+			variables[v.name()] = cty.DynamicVal
+		}
+
+		localsBlocks, err := getBlocksFromFile(f, "locals")
+		if err != nil {
+			return nil, err
+		}
+		for _, bl := range localsBlocks {
+			for name, attr := range bl.Body.Attributes {
+				localExprs[name] = attr.Expr
+			}
+		}
+	}
+
+	if isRootModule {
+		if err = applyVarFiles(dir, declared, variables); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx := &hcl.EvalContext{
+		Variables: map[string]cty.Value{
+			"var":   cty.ObjectVal(variables),
+			"local": cty.DynamicVal,
+		},
+	}
+	ctx.Variables["local"] = cty.ObjectVal(evaluateLocals(localExprs, ctx))
+
+	moduleEvalContexts[dir] = ctx
+	return ctx, nil
+}
+
+// isRootModuleDir returns whether the dir is a root module, which is when none of the module calls, local or installed,
+// calls it
+func isRootModuleDir(dir string, moduleCalls []module) (bool, error) {
+	for _, mod := range moduleCalls {
+		moduleDir, err := resolveModuleDir(mod)
+		if err != nil {
+			return false, err
+		}
+		if moduleDir != "" && path.Clean(moduleDir) == path.Clean(dir) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// evaluateLocals evaluates the locals, where the locals that refer to other locals are evaluated once those are known,
+// and the ones that cannot be evaluated are unknown
+func evaluateLocals(localExprs map[string]hclsyntax.Expression, ctx *hcl.EvalContext) map[string]cty.Value {
+	locals := make(map[string]cty.Value)
+	for name := range localExprs {
+		locals[name] = cty.DynamicVal
+	}
+
+	for progress := true; progress; {
+		progress = false
+		ctx.Variables["local"] = cty.ObjectVal(locals)
+
+		for name, expr := range localExprs {
+			if locals[name].IsWhollyKnown() {
+				continue
+			}
+
+			val, diags := expr.Value(ctx)
+			if !diags.HasErrors() && val.IsWhollyKnown() {
+				locals[name] = val
+				progress = true
+			}
+		}
+	}
+
+	return locals
+}
+
+// variableValue returns the value of the expression for the variable, converted to its type constraint, or an unknown
+// value when it cannot be evaluated
+func variableValue(v variableDefinition, expr hcl.Expression, ctx *hcl.EvalContext) cty.Value {
+	val, diags := expr.Value(ctx)
+	if diags.HasErrors() {
+		return cty.DynamicVal
+	}
+
+	val, err := convertToVariableType(v, val)
+	if err != nil {
+		return cty.DynamicVal
+	}
+	return val
+}
+
+// convertToVariableType converts the value to the type constraint of the variable, as terraform does when the
+// variable is set
+func convertToVariableType(v variableDefinition, val cty.Value) (cty.Value, error) {
+	typeAttr := getAttribute(v.bl.Body, "type")
+	if typeAttr == nil {
+		return val, nil
+	}
+
+	ty, defaults, diags := typeexpr.TypeConstraintWithDefaults(typeAttr.Expr)
+	if diags.HasErrors() {
+		return cty.NilVal, errors.New(diags.Error())
+	}
+
+	if defaults != nil {
+		val = defaults.Apply(val)
+	}
+	return convert.Convert(val, ty)
+}
+
+// applyVarFiles sets the variables of the root module in the dir from the .tfvars files that terraform loads
+// automatically, followed by the ones given with --var-file for the target dir
+func applyVarFiles(dir string, declared map[string]variableDefinition, variables map[string]cty.Value) error {
+	tfvarsFiles := autoLoadedVarFiles(dir)
+	if dir == "." {
+		tfvarsFiles = append(tfvarsFiles, varFiles...)
+	}
+
+	for _, f := range tfvarsFiles {
+		hclFile, diags := hclparse.NewParser().ParseHCLFile(f)
+		if diags.HasErrors() {
+			return fmt.Errorf("failed to parse var file %v: %s", f, diags.Error())
+		}
+
+		attrs, diags := hclFile.Body.JustAttributes()
+		if diags.HasErrors() {
+			return fmt.Errorf("failed to parse var file %v: %s", f, diags.Error())
+		}
+
+		for name, attr := range attrs {
+			if v, exists := declared[name]; exists {
+				variables[name] = variableValue(v, attr.Expr, nil)
+			} else if verbose {
+				reportWarning(&attr.NameRange, fmt.Sprintf("variable '%v' is not declared", name), "the value in the var file is ignored")
+			}
+		}
+	}
+
+	return nil
+}
+
+// autoLoadedVarFiles returns the .tfvars files in the dir that terraform loads without passing them with -var-file
+func autoLoadedVarFiles(dir string) []string {
+	var tfvarsFiles []string
+	if autoFiles, err := filepath.Glob(path.Join(dir, "terraform.tfvars")); err == nil {
+		tfvarsFiles = append(tfvarsFiles, autoFiles...)
+	}
+	if autoFiles, err := filepath.Glob(path.Join(dir, "*.auto.tfvars")); err == nil {
+		tfvarsFiles = append(tfvarsFiles, autoFiles...)
+	}
+	return tfvarsFiles
+}
@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+func TestEqualToVariableDefinitionWithModuleContext(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"main.tf": `variable "default_tags" {
+  type    = map(string)
+  default = { team = "a" }
+}
+
+variable "region" {
+  default = "eu-west-1"
+}
+
+variable "enable_nat" {}
+
+locals {
+  enable_nat = true
+  name       = "${local.prefix}-x"
+  prefix     = "app"
+  zones      = [var.region]
+  unknown    = aws_vpc.main.id
+}
+`,
+		"terraform.tfvars": `region = "us-east-1"
+`,
+	})
+	chdirForTest(t, dir)
+
+	ctx, err := getModuleEvalContext(".", nil)
+	if err != nil {
+		t.Fatalf("getModuleEvalContext() error = %v", err)
+	}
+
+	testCases := []struct {
+		name       string
+		assignment string
+		variable   string
+		expected   bool
+	}{
+		{name: "local", assignment: "local.enable_nat", variable: "type = bool\ndefault = true", expected: true},
+		{name: "local with other value", assignment: "local.enable_nat", variable: "default = false", expected: false},
+		{name: "local referring to local", assignment: "local.name", variable: `default = "app-x"`, expected: true},
+		{name: "variable default, that can be overridden", assignment: "var.default_tags", variable: "type = map(string)\ndefault = { team = \"a\" }", expected: false},
+		{name: "variable from tfvars", assignment: "var.region", variable: `default = "us-east-1"`, expected: true},
+		{name: "variable default overridden by tfvars", assignment: "var.region", variable: `default = "eu-west-1"`, expected: false},
+		{name: "local referring to variable", assignment: "local.zones", variable: `default = ["us-east-1"]`, expected: true},
+		{name: "variable without default", assignment: "var.enable_nat", variable: "default = true", expected: false},
+		{name: "unknown local", assignment: "local.unknown", variable: "default = null", expected: false},
+		{name: "converted to variable type", assignment: "1", variable: "type = string\ndefault = \"1\"", expected: true},
+		{name: "not converted without type", assignment: "1", variable: `default = "1"`, expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assign := parseTestAttribute(t, "x = "+tc.assignment)
+			v := parseTestVariable(t, "variable \"x\" {\n"+tc.variable+"\n}")
+
+			if result := equalToVariableDefinition(assign, ctx, v); result != tc.expected {
+				t.Errorf("equalToVariableDefinition(%s, %s) = %t; want %t", tc.assignment, tc.variable, result, tc.expected)
+			}
+		})
+	}
+}
+
+func TestModuleEvalContextVariables(t *testing.T) {
+	module := `variable "a" {
+  default = "x"
+}
+`
+	testCases := []struct {
+		name     string
+		files    map[string]string
+		varFiles []string
+		dir      string
+		known    bool
+	}{
+		{
+			name: "called module with var file",
+			files: map[string]string{
+				"main.tf":                    "module \"m\" {\n  source = \"./modules/m\"\n}\n",
+				"modules/m/main.tf":          module,
+				"modules/m/terraform.tfvars": "a = \"x\"\n",
+			},
+			dir:   "modules/m",
+			known: false,
+		},
+		{
+			name:  "default without var files",
+			files: map[string]string{"main.tf": module + "terraform {\n  backend \"s3\" {}\n}\n"},
+			dir:   ".",
+			known: false,
+		},
+		{
+			name:  "auto-loaded var file",
+			files: map[string]string{"main.tf": module, "other.auto.tfvars": "a = \"x\"\n"},
+			dir:   ".",
+			known: true,
+		},
+		{
+			name:  "auto-loaded var file with other value",
+			files: map[string]string{"main.tf": module, "terraform.tfvars": "a = \"y\"\n"},
+			dir:   ".",
+			known: false,
+		},
+		{
+			name:     "var file",
+			files:    map[string]string{"main.tf": module, "prod.tfvars": "a = \"x\"\n"},
+			varFiles: []string{"prod.tfvars"},
+			dir:      ".",
+			known:    true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTestFiles(t, dir, tc.files)
+			chdirForTest(t, dir)
+			varFiles = tc.varFiles
+			defer func() { varFiles = nil }()
+
+			moduleCalls, err := getModuleCallTree([]string{"main.tf"})
+			if err != nil {
+				t.Fatalf("getModuleCallTree() error = %v", err)
+			}
+			ctx, err := getModuleEvalContext(tc.dir, moduleCalls)
+			if err != nil {
+				t.Fatalf("getModuleEvalContext() error = %v", err)
+			}
+
+			// only the var files of a root module set the value, as the default can be overridden, and callers
+			// might set another value
+			assign := parseTestAttribute(t, "a = var.a")
+			v := parseTestVariable(t, "variable \"a\" {\ndefault = \"x\"\n}")
+			if result := equalToVariableDefinition(assign, ctx, v); result != tc.known {
+				t.Errorf("equalToVariableDefinition(var.a) = %t; want %t", result, tc.known)
+			}
+		})
+	}
+}
+
+func parseTestAttribute(t *testing.T, src string) expression {
+	hclFile, diags := hclsyntax.ParseConfig([]byte(src), "dummy.tf", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		t.Fatalf("'%s' is not valid HCL: diagnostics: %v", src, diags)
+	}
+	for _, attr := range hclFile.Body.(*hclsyntax.Body).Attributes {
+		return expression{attr}
+	}
+	t.Fatalf("'%s' has no attribute", src)
+	return expression{}
+}
+
+func parseTestVariable(t *testing.T, src string) variableDefinition {
+	hclFile, diags := hclsyntax.ParseConfig([]byte(src), "dummy.tf", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		t.Fatalf("'%s' is not valid HCL: diagnostics: %v", src, diags)
+	}
+	return variableDefinition{hclFile.Body.(*hclsyntax.Body).Blocks[0]}
+}
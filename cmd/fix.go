@@ -1,6 +1,10 @@
 package cmd
 
 import (
+	"fmt"
+	"os"
+	"sort"
+
 	"github.com/spf13/cobra"
 )
 
@@ -12,6 +16,8 @@ var fixCmd = &cobra.Command{
 
 func init() {
 	rootCmd.AddCommand(fixCmd)
+	addRuleFlags(fixCmd)
+	fixCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print a diff of the fixes instead of applying them")
 }
 
 func runFixCmd(cmd *cobra.Command, args []string) error {
@@ -20,44 +26,70 @@ func runFixCmd(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	tfFiles, err := getTerraformFiles()
-	if err != nil {
+	if err = applyConfig(cmd); err != nil {
 		return err
 	}
 
-	if err = performUnneededAttrFix(tfFiles); err != nil {
+	tfFiles, err := getTerraformFiles()
+	if err != nil {
 		return err
 	}
 
-	if err = performFormatUsageFix(tfFiles); err != nil {
+	rules, err := getSelectedRules()
+	if err != nil {
 		return err
 	}
 
-	return nil
-}
+	for _, r := range rules {
+		if r.fix == nil {
+			continue
+		}
 
-func performUnneededAttrFix(tfFiles []string) error {
-	report, err := checkForUnneededAttributeAssignments(tfFiles)
-	if err != nil {
-		return err
+		if err = r.fix(tfFiles); err != nil {
+			return err
+		}
 	}
 
-	err = removeUnneededAttributes(report)
-	if err != nil {
-		return err
+	if dryRun {
+		err = printPendingDiffs(cmd)
 	}
-	return nil
+
+	if skippedErr := skippedFilesError(cmd); skippedErr != nil {
+		return skippedErr
+	}
+	return err
 }
 
-func performFormatUsageFix(tfFiles []string) error {
-	report, err := checkForFormatUsage(tfFiles)
-	if err != nil {
-		return err
+// printPendingDiffs prints the diff for every file that would be changed, and fails when there are any
+func printPendingDiffs(cmd *cobra.Command) error {
+	var filenames []string
+	for filename := range pendingFiles {
+		filenames = append(filenames, filename)
 	}
+	sort.Strings(filenames)
 
-	err = convertFormatUsageToInterpolation(report)
-	if err != nil {
-		return err
+	changed := false
+	for _, filename := range filenames {
+		original, err := os.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+
+		diff, err := unifiedDiff(filename, original, pendingFiles[filename])
+		if err != nil {
+			return err
+		}
+		if diff != "" {
+			fmt.Print(diff)
+			changed = true
+		}
 	}
+
+	if changed {
+		cmd.SilenceErrors = true
+		cmd.SilenceUsage = true
+		return errViolationsFound
+	}
+
 	return nil
 }
@@ -3,18 +3,23 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"path"
+	"strings"
 
 	"github.com/hashicorp/hcl/v2"
-	"github.com/hashicorp/hcl/v2/hclparse"
 	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
 )
 
-type formatUsages map[string][]formatInvocation
-
 type hclBlockId struct {
 	typeName string
 	labels   []string
+
+	// index is the position of the block among its sibling blocks with the same type and labels, to tell apart
+	// repeated blocks, like multiple "ingress" blocks in a resource
+	index int
 }
 
 type hclAddress struct {
@@ -23,154 +28,183 @@ type hclAddress struct {
 }
 
 type formatInvocation struct {
-	tokens     []hclsyntax.Token
+	call       *hclsyntax.FunctionCallExpr
+	attr       *hclsyntax.Attribute
 	hclAddress hclAddress
 
-	// TODO: remove expr, when fully switched over to hclAddress
-	expr hcl.Expression
+	// replacement is the string template that replaces the call, unless conversionErr explains why it cannot be
+	// converted
+	replacement   string
+	conversionErr error
+}
+
+func init() {
+	registerRule(rule{
+		id:          "format-usage",
+		name:        "format() usages",
+		description: "Usages of format() that can be written as a string template",
+		severity:    severityWarning,
+		check:       checkFormatUsages,
+		fix:         performFormatUsageFix,
+	})
 }
 
 // CHECK
 
-func checkForFormatUsage(files []string) (formatUsages, error) {
-	result := make(map[string][]formatInvocation)
-	for _, f := range files {
-		violations, err := checkForFormatUsageInFile(f)
+// check for format() usage
+func checkFormatUsages(tfFiles []string) ([]violation, error) {
+	var violations []violation
+	for _, f := range tfFiles {
+		invocations, err := checkForFormatUsageInFile(f)
 		if err != nil {
 			return nil, err
 		}
 
-		if len(violations) > 0 {
-			result[f] = violations
+		for _, invoke := range invocations {
+			v := violation{
+				group:   fmt.Sprintf("file '%v'", f),
+				address: invoke.hclAddress,
+				rng:     invoke.call.Range(),
+				text:    sourceText(invoke.call.Range()),
+			}
+			if invoke.conversionErr != nil {
+				v.note = "cannot be fixed: " + invoke.conversionErr.Error()
+			} else {
+				v.replacement = &invoke.replacement
+			}
+			violations = append(violations, v)
 		}
 	}
-	return result, nil
+
+	return violations, nil
 }
 
-func checkForFormatUsageInFile(file string) ([]formatInvocation, error) {
-	tokens, err := readHclTokens(file)
-	if err != nil {
-		return nil, err
+func checkForFormatUsageInFile(filename string) ([]formatInvocation, error) {
+	hclFile := parseHclFileOrSkip(filename)
+	if hclFile == nil {
+		return nil, nil
 	}
 
-	var violationTokens []hclsyntax.Token
-	for _, token := range tokens {
-		if isFormatToken(token) {
-			violationTokens = append(violationTokens, token)
+	var result []formatInvocation
+	walkAttributes(hclFile.Body.(*hclsyntax.Body), nil, func(attr *hclsyntax.Attribute, address hclAddress) {
+		for _, invoke := range findFormatInvocations(attr.Expr, hclFile.Bytes) {
+			invoke.attr = attr
+			invoke.hclAddress = address
+			result = append(result, invoke)
 		}
-	}
-
-	result, err := convertTokensToExpressions(file, tokens, violationTokens)
-	if err != nil {
-		return nil, err
-	}
+	})
 
 	return result, nil
 }
 
-func convertTokensToExpressions(filename string, fileTokens []hclsyntax.Token, violationTokens []hclsyntax.Token) ([]formatInvocation, error) {
-	hclFile, diags := hclparse.NewParser().ParseHCLFile(filename)
-	if diags.HasErrors() {
-		return nil, errors.New("failed to parse TF file: " + diags.Error())
-	}
-
+// findFormatInvocations returns the format() calls in the expression, where a call that is nested in a convertible
+// call is left out, as it is converted as part of that call already
+func findFormatInvocations(expr hclsyntax.Expression, src []byte) []formatInvocation {
 	var result []formatInvocation
-	for _, t := range violationTokens {
-		expr := hclFile.OutermostExprAtPos(t.Range.Start)
-
-		result = append(result, formatInvocation{
-			getAllTokensForExpression(fileTokens, expr),
-			getHclAddress(hclFile, expr),
-			expr,
-		})
-	}
-	return result, nil
-}
-
-func getAllTokensForExpression(fileTokens []hclsyntax.Token, expr hcl.Expression) []hclsyntax.Token {
-	start := 0
-	for i, t := range fileTokens {
-		if t.Range.Start == expr.Range().Start {
-			start = i
+	var convertedRanges []hcl.Range
+	for _, call := range findFunctionCalls(expr, "format") {
+		if isNestedInAny(call.Range(), convertedRanges) {
+			continue
 		}
 
-		if t.Range.End == expr.Range().End {
-			return fileTokens[start : i+1]
+		replacement, conversionErr := convertFormatToTemplate(call, src)
+		if conversionErr == nil {
+			convertedRanges = append(convertedRanges, call.Range())
 		}
+		result = append(result, formatInvocation{call: call, replacement: replacement, conversionErr: conversionErr})
 	}
-
-	panic("cannot reach")
+	return result
 }
 
-func getHclAddress(hclFile *hcl.File, expr hcl.Expression) hclAddress {
-	var blIds []hclBlockId
-
-	blocks := hclFile.BlocksAtPos(expr.Range().Start)
-	for _, bl := range blocks {
-		blIds = append(blIds, hclBlockId{bl.Type, bl.Labels})
+func isNestedInAny(rng hcl.Range, ranges []hcl.Range) bool {
+	for _, r := range ranges {
+		if rng.Start.Byte >= r.Start.Byte && rng.End.Byte <= r.End.Byte {
+			return true
+		}
 	}
-	attr := hclFile.AttributeAtPos(expr.Range().Start)
-
-	return hclAddress{blIds, attr.Name}
-}
-
-func isFormatToken(token hclsyntax.Token) bool {
-	return token.Type == hclsyntax.TokenIdent && isTokenText(token, "format")
+	return false
 }
 
-func (invoke formatInvocation) string() string {
-	var str string
-	for _, s := range invoke.tokens {
-		str = str + string(s.Bytes)
+func (address hclAddress) string() string {
+	var parts []string
+	for _, bl := range address.blocks {
+		parts = append(parts, bl.typeName)
+		parts = append(parts, bl.labels...)
+		if bl.index > 0 {
+			parts[len(parts)-1] += fmt.Sprintf("[%d]", bl.index)
+		}
 	}
-	return str
-}
-
-func (invoke formatInvocation) location() string {
-	r := invoke.expr.Range()
-	return fmt.Sprintf("%v:L%d:%d-%d", r.Filename, r.Start.Line, r.Start.Column, r.End.Column)
+	if address.attrName != "" {
+		parts = append(parts, address.attrName)
+	}
+	return strings.Join(parts, ".")
 }
 
 // FIX
 
-func convertFormatUsageToInterpolation(report formatUsages) error {
-	for filename, formatInvocations := range report {
-		if len(formatInvocations) == 0 {
+func performFormatUsageFix(tfFiles []string) error {
+	for _, f := range tfFiles {
+		invocations, err := checkForFormatUsageInFile(f)
+		if err != nil {
+			return err
+		}
+
+		var replacements []exprReplacement
+		for _, invoke := range invocations {
+			if invoke.conversionErr != nil {
+				rng := invoke.call.Range()
+				reportWarning(&rng, "format() call is not converted", invoke.conversionErr.Error())
+				continue
+			}
+			replacements = append(replacements, exprReplacement{
+				address:   invoke.hclAddress,
+				exprRange: invoke.attr.Expr.Range(),
+				rng:       invoke.call.Range(),
+				text:      invoke.replacement,
+			})
+		}
+
+		if len(replacements) == 0 {
 			continue
 		}
-		err := convertFormatUsageToInterpolationForFile(filename, formatInvocations)
-		if err != nil {
+		if err = replaceInAttributes(f, replacements); err != nil {
 			return err
 		}
 	}
-	return nil
-}
 
-func convertFormatUsageToInterpolationForFile(filename string, formatInvocations []formatInvocation) error {
-	return patchFile(filename, func(hclFile *hclwrite.File) (*hclwrite.File, error) {
-		for _, fi := range formatInvocations {
-			body, attrName := getAttributeForWrite(hclFile, fi.hclAddress)
-			body.SetAttributeRaw(attrName, convertFormatToInterpolation(fi.tokens))
-		}
-		return hclFile, nil
-	})
+	return nil
 }
 
+// getAttributeForWrite returns the body that contains the attribute at the address, by walking down the nested blocks,
+// or nil when there is no such block
 func getAttributeForWrite(hclFile *hclwrite.File, address hclAddress) (*hclwrite.Body, string) {
 	body := hclFile.Body()
 
 	for _, blAddr := range address.blocks {
-		for _, bl := range hclFile.Body().Blocks() {
-			if isAddr(blAddr, bl) {
-				body = bl.Body()
-				break
-			}
+		bl := findBlockForWrite(body, blAddr)
+		if bl == nil {
+			return nil, address.attrName
 		}
+		body = bl.Body()
 	}
 
 	return body, address.attrName
 }
 
+func findBlockForWrite(body *hclwrite.Body, blAddr hclBlockId) *hclwrite.Block {
+	index := 0
+	for _, bl := range body.Blocks() {
+		if !isAddr(blAddr, bl) {
+			continue
+		}
+		if index == blAddr.index {
+			return bl
+		}
+		index++
+	}
+	return nil
+}
+
 func isAddr(blAddr hclBlockId, bl *hclwrite.Block) bool {
 	return blAddr.typeName == bl.Type() && equalsLabels(blAddr.labels, bl.Labels())
 }
@@ -187,162 +221,192 @@ func equalsLabels(l1 []string, l2 []string) bool {
 	return true
 }
 
-func convertFormatToInterpolation(tokens []hclsyntax.Token) hclwrite.Tokens {
-	var resultTokens []*hclwrite.Token
-	for i := 0; i < len(tokens); i++ {
-		t := tokens[i]
-		if t.Type == hclsyntax.TokenIdent && string(t.Bytes) == "format" {
-			newTokens, consumedTokens := parseFormatAndReturnInterpolationTokens(tokens[i:])
-			resultTokens = append(resultTokens, newTokens...)
-			i += consumedTokens - 1 // account for the next loop increase
-		} else {
-			resultTokens = append(resultTokens, toHclwriteToken(t))
+// convertFormatToInterpolation converts the format() calls in the expression, leaving the ones that cannot be
+// converted faithfully untouched
+func convertFormatToInterpolation(expr hclsyntax.Expression, src []byte) string {
+	var replacements []exprReplacement
+	for _, invoke := range findFormatInvocations(expr, src) {
+		if invoke.conversionErr != nil {
+			continue
 		}
+		replacements = append(replacements, exprReplacement{rng: invoke.call.Range(), text: invoke.replacement})
 	}
-	return resultTokens
+	return spliceReplacements(src, expr.Range(), replacements)
 }
 
-func parseFormatAndReturnInterpolationTokens(tokens []hclsyntax.Token) ([]*hclwrite.Token, int) {
-	var resultTokens []*hclwrite.Token
-
-	i := 0
+// convertFormatToTemplate converts the format() call to an equivalent string template, which is a heredoc when the
+// format string is one
+func convertFormatToTemplate(call *hclsyntax.FunctionCallExpr, src []byte) (string, error) {
+	parts, err := formatCallParts(call, src)
+	if err != nil {
+		return "", err
+	}
 
-	// eat format token
-	i++
+	if h, ok := parseHeredoc(call.Args[0], src); ok {
+		if template, ok := buildHeredocTemplate(parts, h); ok {
+			return template, nil
+		}
+	}
+	return buildQuotedTemplate(parts), nil
+}
 
-	// eat open bracket token
-	i++
+// formatCallParts returns the template parts that the format() call evaluates to, where the arguments are taken from
+// the source by their range, so that arguments of any complexity are preserved as is
+func formatCallParts(call *hclsyntax.FunctionCallExpr, src []byte) ([]templatePart, error) {
+	if call.ExpandFinal {
+		return nil, errors.New("arguments that are expanded with '...' are not supported")
+	}
+	if len(call.Args) == 0 {
+		return nil, errors.New("format string is missing")
+	}
 
-	fmtString, tokensConsumed := getFormatString(tokens[i:])
-	i += tokensConsumed
+	fmtParts, ok := formatStringParts(call.Args[0], src)
+	if !ok {
+		return nil, errors.New("format string is not a literal string or template")
+	}
 
-	fmtArgs, tokensConsumed := getFormatArgs(tokens[i:])
-	i += tokensConsumed
+	// the interpolations of a templated format string are kept as is, which is only safe when their values are known
+	// to contain no verbs
+	if template, ok := call.Args[0].(*hclsyntax.TemplateExpr); ok {
+		if err := checkTemplateInterpolationsWithoutVerbs(template, src); err != nil {
+			return nil, err
+		}
+	}
 
-	// push opening quote token
-	resultTokens = append(resultTokens, quoteToken()) // TODO: fix the type that we use here (is now closing quote, should be opening)
-	// parse the fmt string
-	resultTokens = append(resultTokens, parseFmtString(fmtString, fmtArgs)...)
-	// push closing quote token
-	resultTokens = append(resultTokens, quoteToken())
+	var segments []fmtSegment
+	nextArg := 0
+	for _, fp := range fmtParts {
+		if fp.expr != "" {
+			segments = append(segments, fmtSegment{expr: fp.expr})
+			continue
+		}
 
-	return resultTokens, i
-}
+		literalSegments, next, err := parseFormatVerbsFrom(fp.literal, nextArg)
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, literalSegments...)
+		nextArg = next
+	}
 
-func getFormatString(tokens []hclsyntax.Token) (string, int) {
-	var bytes []byte
-	var tokensConsumed int
+	decls, err := getModuleDeclarations(path.Dir(call.Range().Filename))
+	if err != nil {
+		return nil, err
+	}
 
-	// consume opening quote token
-	tokens = tokens[1:]
-	tokensConsumed++
+	args := call.Args[1:]
+	var fmtArgs []fmtArg
+	for _, arg := range args {
+		fmtArgs = append(fmtArgs, getFmtArg(arg, decls, 0))
+	}
+	if err := checkConvertibleFormat(segments, fmtArgs); err != nil {
+		return nil, err
+	}
 
-	for _, t := range tokens {
-		tokensConsumed++
-		if t.Type == hclsyntax.TokenCQuote {
-			break
-		} else {
-			bytes = append(bytes, t.Bytes...)
+	var parts []templatePart
+	for _, seg := range segments {
+		switch {
+		case seg.verb != nil:
+			parts = append(parts, argTemplateParts(args[seg.verb.argIndex], src)...)
+		case seg.expr != "":
+			parts = append(parts, templatePart{expr: seg.expr})
+		default:
+			parts = append(parts, templatePart{literal: seg.literal})
 		}
 	}
 
-	return string(bytes), tokensConsumed
+	return parts, nil
 }
 
-func getFormatArgs(tokens []hclsyntax.Token) ([][]hclsyntax.Token, int) {
-	var resultTokens [][]hclsyntax.Token
-	var tokensConsumed int
-
-	start := 0
-	for i, t := range tokens {
-		tokensConsumed++
-
-		if start != 0 && // skip the first comma
-			(t.Type == hclsyntax.TokenComma || t.Type == hclsyntax.TokenCParen) {
-			resultTokens = append(resultTokens, tokens[start:i])
+// checkTemplateInterpolationsWithoutVerbs returns why the interpolations of the templated format string might add
+// verbs to it, if so, as only literal values are known to have no "%"
+func checkTemplateInterpolationsWithoutVerbs(template *hclsyntax.TemplateExpr, src []byte) error {
+	for _, part := range template.Parts {
+		if !isInterpolation(part, src) {
+			continue
 		}
 
-		if t.Type == hclsyntax.TokenComma {
-			start = i + 1 // set start to next token after the current comma
+		interpolation := string(part.Range().SliceBytes(src))
+		val, ok := staticValue(part)
+		if !ok || !val.Type().IsPrimitiveType() {
+			return fmt.Errorf("the value of interpolation '%v' in the format string is unknown, and might contain verbs", interpolation)
 		}
-
-		if t.Type == hclsyntax.TokenCParen {
-			break
+		str, err := convert.Convert(val, cty.String)
+		if err != nil || strings.Contains(str.AsString(), "%") {
+			return fmt.Errorf("interpolation '%v' in the format string contains verbs", interpolation)
 		}
 	}
-
-	return resultTokens, tokensConsumed
+	return nil
 }
 
-func parseFmtString(fmtString string, fmtArgs [][]hclsyntax.Token) []*hclwrite.Token {
-	var resultTokens []*hclwrite.Token
-	var stringFragment []byte
-
-	for i := 0; i < len(fmtString); i++ {
-		s := fmtString[i:min(i+2, len(fmtString))]
-		if s == "%s" {
-			arg := fmtArgs[0]
-			if arg[0].Type == hclsyntax.TokenOQuote {
-				// expression is string literal, so we can inline it
-				stringFragment = append(stringFragment, arg[1].Bytes...)
-			} else {
-				// otherwise we need to start a template interpretation
-				resultTokens = append(resultTokens, stringLiteralToken(stringFragment))
-				stringFragment = nil
-
-				resultTokens = append(resultTokens, openingTemplate())
-				resultTokens = append(resultTokens, toHclwriteTokens(arg)...)
-				resultTokens = append(resultTokens, closingTemplate())
-			}
-
-			fmtArgs = fmtArgs[1:] // consume the arg
-			i += 1                // consume the %s
-		} else {
-			stringFragment = append(stringFragment, fmtString[i])
-		}
+// formatStringParts returns the parts of the format string, which is either a literal string, or a template (including
+// heredocs) without directives
+func formatStringParts(expr hclsyntax.Expression, src []byte) ([]templatePart, bool) {
+	if literal, ok := stringLiteralValue(expr); ok {
+		return []templatePart{{literal: literal}}, true
 	}
 
-	// append last string fragment
-	resultTokens = append(resultTokens, stringLiteralToken(stringFragment))
-
-	return resultTokens
+	if template, ok := expr.(*hclsyntax.TemplateExpr); ok {
+		return templateExprParts(template, src)
+	}
+	return nil, false
 }
 
-func toHclwriteTokens(tokens []hclsyntax.Token) []*hclwrite.Token {
-	var resultTokens []*hclwrite.Token
-	for _, t := range tokens {
-		resultTokens = append(resultTokens, toHclwriteToken(t))
+// argTemplateParts returns the template parts that interpolate the argument, where string literals, templates and
+// convertible format() and join() calls are inlined
+func argTemplateParts(arg hclsyntax.Expression, src []byte) []templatePart {
+	if literal, ok := stringLiteralValue(arg); ok {
+		return []templatePart{{literal: literal}}
 	}
-	return resultTokens
-}
 
-func toHclwriteToken(token hclsyntax.Token) *hclwrite.Token {
-	return &hclwrite.Token{
-		Type:  token.Type,
-		Bytes: token.Bytes,
+	switch e := arg.(type) {
+	case *hclsyntax.FunctionCallExpr:
+		switch e.Name {
+		case "format":
+			if parts, err := formatCallParts(e, src); err == nil {
+				return parts
+			}
+		case "join":
+			if _, parts, err := joinCallParts(e, src); err == nil {
+				return parts
+			}
+		}
+	case *hclsyntax.TemplateWrapExpr:
+		return []templatePart{{expr: string(e.Wrapped.Range().SliceBytes(src))}}
+	case *hclsyntax.TemplateExpr:
+		if parts, ok := templateExprParts(e, src); ok {
+			return parts
+		}
 	}
-}
 
-func quoteToken() *hclwrite.Token {
-	return &hclwrite.Token{
-		Type:  hclsyntax.TokenOQuote,
-		Bytes: []byte(`"`)}
+	return []templatePart{{expr: string(arg.Range().SliceBytes(src))}}
 }
 
-func stringLiteralToken(b []byte) *hclwrite.Token {
-	return &hclwrite.Token{
-		Type:  hclsyntax.TokenQuotedLit,
-		Bytes: b}
-}
+// templateExprParts returns the parts of the template, when it consists of only literals and interpolations, so
+// without any directives
+func templateExprParts(template *hclsyntax.TemplateExpr, src []byte) ([]templatePart, bool) {
+	var parts []templatePart
+	for _, part := range template.Parts {
+		if literal, ok := part.(*hclsyntax.LiteralValueExpr); ok && literal.Val.Type() == cty.String {
+			parts = append(parts, templatePart{literal: literal.Val.AsString()})
+			continue
+		}
 
-func openingTemplate() *hclwrite.Token {
-	return &hclwrite.Token{
-		Type:  hclsyntax.TokenTemplateInterp,
-		Bytes: []byte(`${`)}
+		if !isInterpolation(part, src) {
+			return nil, false
+		}
+		parts = append(parts, templatePart{expr: string(part.Range().SliceBytes(src))})
+	}
+	return parts, true
 }
 
-func closingTemplate() *hclwrite.Token {
-	return &hclwrite.Token{
-		Type:  hclsyntax.TokenTemplateSeqEnd,
-		Bytes: []byte(`}`)}
+// isInterpolation returns whether the template part is written as "${...}", and not as a directive
+func isInterpolation(part hclsyntax.Expression, src []byte) bool {
+	switch part.(type) {
+	case *hclsyntax.ConditionalExpr, *hclsyntax.TemplateJoinExpr:
+		return false
+	}
+
+	before := strings.TrimRight(string(src[:part.Range().Start.Byte]), " \t\r\n~")
+	return strings.HasSuffix(before, "${")
 }
@@ -1,14 +1,63 @@
 package cmd
 
 import (
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
 )
 
+// useFormatTestModule makes the expressions that are parsed from "dummy.tf" part of a module, that declares the types
+// of the variables they use, as only arguments of known types are converted
+func useFormatTestModule(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"variables.tf": `variable "hoi" {
+  type = string
+}
+variable "dag" {
+  type = string
+}
+variable "name" {
+  type = string
+}
+variable "prefix" {
+  type = string
+}
+variable "pct" {
+  type = number
+}
+variable "ratio" {
+  type = number
+}
+variable "enabled" {
+  type = bool
+}
+variable "list" {
+  type = list(string)
+}
+variable "map" {
+  type = map(string)
+}
+variable "untyped" {}
+
+locals {
+  dag = "x"
+}
+`,
+	})
+	chdirForTest(t, dir)
+}
+
 func TestConvertFormatToInterpolation(t *testing.T) {
+	useFormatTestModule(t)
 	testCases := []struct {
 		name     string
 		expr     string
@@ -34,6 +83,51 @@ func TestConvertFormatToInterpolation(t *testing.T) {
 			expr:     `format("%s-%s", var.hoi, local.dag)`,
 			expected: `"${var.hoi}-${local.dag}"`,
 		},
+		{
+			name:     "verbs: %d, %v and %t are interpolated",
+			expr:     `format("%d-%v-%t", count.index, var.name, var.enabled)`,
+			expected: `"${count.index}-${var.name}-${var.enabled}"`,
+		},
+		{
+			name:     "verbs: %% is a literal percent sign",
+			expr:     `format("%s%%", var.pct)`,
+			expected: `"${var.pct}%"`,
+		},
+		{
+			name:     "verbs: explicit argument indexes",
+			expr:     `format("%[2]s-%[1]s-%s", var.hoi, var.dag)`,
+			expected: `"${var.dag}-${var.hoi}-${var.dag}"`,
+		},
+		{
+			name:     "string literal: empty string",
+			expr:     `format("%s-%s", "", var.hoi)`,
+			expected: `"-${var.hoi}"`,
+		},
+		{
+			name:     "unconvertible: width flag is left untouched",
+			expr:     `format("%05d", var.count)`,
+			expected: `format("%05d", var.count)`,
+		},
+		{
+			name:     "unconvertible: precision is left untouched",
+			expr:     `format("%.2f", var.ratio)`,
+			expected: `format("%.2f", var.ratio)`,
+		},
+		{
+			name:     "unconvertible: %q is left untouched",
+			expr:     `format("%q", var.name)`,
+			expected: `format("%q", var.name)`,
+		},
+		{
+			name:     "unconvertible: too few arguments",
+			expr:     `format("%s-%s", var.name)`,
+			expected: `format("%s-%s", var.name)`,
+		},
+		{
+			name:     "unconvertible: unused argument",
+			expr:     `format("%s", var.hoi, var.dag)`,
+			expected: `format("%s", var.hoi, var.dag)`,
+		},
 		{
 			name:     "array: single item",
 			expr:     `["hoi"]`,
@@ -42,7 +136,7 @@ func TestConvertFormatToInterpolation(t *testing.T) {
 		{
 			name:     "array: multiple items item",
 			expr:     `["hoi", "dag"]`,
-			expected: `["hoi","dag"]`,
+			expected: `["hoi", "dag"]`,
 		},
 		{
 			name:     "array: with format call",
@@ -52,27 +146,413 @@ func TestConvertFormatToInterpolation(t *testing.T) {
 		{
 			name:     "array: with many format calls",
 			expr:     `[format("hoi"), format("%s-%s", var.hoi, local.dag)]`,
-			expected: `["hoi","${var.hoi}-${local.dag}"]`,
+			expected: `["hoi", "${var.hoi}-${local.dag}"]`,
+		},
+		{
+			name:     "nested call: commas in arguments",
+			expr:     `format("%s", join(",", var.list))`,
+			expected: `"${join(",", var.list)}"`,
+		},
+		{
+			name:     "nested call: multiple arguments",
+			expr:     `format("%s-%s", replace(var.hoi, "a", "b"), var.hoi)`,
+			expected: `"${replace(var.hoi, "a", "b")}-${var.hoi}"`,
+		},
+		{
+			name:     "unconvertible: arguments of unknown type",
+			expr:     `format("%v-%v", [1, 2][0], { a = "b" }.a)`,
+			expected: `format("%v-%v", [1, 2][0], { a = "b" }.a)`,
+		},
+		{
+			name:     "unconvertible: %v with a list, which is formatted as JSON",
+			expr:     `format("%v", var.list)`,
+			expected: `format("%v", var.list)`,
+		},
+		{
+			name:     "unconvertible: %v with a list among other text",
+			expr:     `format("%v-x", var.list)`,
+			expected: `format("%v-x", var.list)`,
+		},
+		{
+			name:     "unconvertible: %s with a list",
+			expr:     `format("%s-x", var.list)`,
+			expected: `format("%s-x", var.list)`,
+		},
+		{
+			name:     "unconvertible: %s with an untyped variable",
+			expr:     `format("%s-x", var.untyped)`,
+			expected: `format("%s-x", var.untyped)`,
+		},
+		{
+			name:     "unconvertible: %v with a number, which might be formatted in scientific notation",
+			expr:     `format("%v-x", var.ratio)`,
+			expected: `format("%v-x", var.ratio)`,
+		},
+		{
+			name:     "unconvertible: %v with a large number literal",
+			expr:     `format("%v-x", 1000000)`,
+			expected: `format("%v-x", 1000000)`,
+		},
+		{
+			name:     "%v with a small number literal",
+			expr:     `format("%v-x", 12)`,
+			expected: `"${12}-x"`,
+		},
+		{
+			name:     "unconvertible: %d with a number that might not be whole",
+			expr:     `format("%d-x", var.ratio)`,
+			expected: `format("%d-x", var.ratio)`,
+		},
+		{
+			name:     "unconvertible: %d with a fraction",
+			expr:     `format("%d-x", 1.5)`,
+			expected: `format("%d-x", 1.5)`,
+		},
+		{
+			name:     "%d with a whole number",
+			expr:     `format("%d-x", length(var.list))`,
+			expected: `"${length(var.list)}-x"`,
+		},
+		{
+			name:     "unconvertible: %t with a string",
+			expr:     `format("%t-x", var.hoi)`,
+			expected: `format("%t-x", var.hoi)`,
+		},
+		{
+			name:     "%s with a number",
+			expr:     `format("%s%%", var.pct)`,
+			expected: `"${var.pct}%"`,
+		},
+		{
+			name:     "unconvertible: single verb with a number, as the template would return the number itself",
+			expr:     `format("%s", var.pct)`,
+			expected: `format("%s", var.pct)`,
+		},
+		{
+			name:     "single verb with a string",
+			expr:     `format("%s", var.hoi)`,
+			expected: `"${var.hoi}"`,
+		},
+		{
+			name:     "conditional argument",
+			expr:     `format("%s-x", var.enabled ? "a" : "b")`,
+			expected: `"${var.enabled ? "a" : "b"}-x"`,
+		},
+		{
+			name:     "format inside format: inlined",
+			expr:     `format("%s-%s", format("%s.%s", var.hoi, "x"), var.dag)`,
+			expected: `"${var.hoi}.x-${var.dag}"`,
+		},
+		{
+			name:     "format inside format: unconvertible inner call is kept",
+			expr:     `format("%s-%s", format("%05d", var.count), var.dag)`,
+			expected: `"${format("%05d", var.count)}-${var.dag}"`,
+		},
+		{
+			name:     "format inside format: unconvertible outer call",
+			expr:     `format("%05d-%s", var.count, format("%s", var.dag))`,
+			expected: `format("%05d-%s", var.count, "${var.dag}")`,
+		},
+		{
+			name:     "template argument: inlined",
+			expr:     `format("%s-x", "${var.hoi}.y")`,
+			expected: `"${var.hoi}.y-x"`,
+		},
+		{
+			name:     "template argument: directives are interpolated as a whole",
+			expr:     `format("%s-x", "%{if var.enabled}y%{endif}")`,
+			expected: `"${"%{if var.enabled}y%{endif}"}-x"`,
+		},
+		{
+			name:     "templated format string: literal interpolations are kept",
+			expr:     `format("${"a"}-%s-${1}", var.hoi)`,
+			expected: `"${"a"}-${var.hoi}-${1}"`,
+		},
+		{
+			name:     "templated format string: verbs consume arguments across interpolations",
+			expr:     `format("%s${"-"}%s", var.hoi, var.dag)`,
+			expected: `"${var.hoi}${"-"}${var.dag}"`,
+		},
+		{
+			name:     "unconvertible: templated format string with a variable, which might contain verbs",
+			expr:     `format("${var.prefix}-%s", var.hoi)`,
+			expected: `format("${var.prefix}-%s", var.hoi)`,
+		},
+		{
+			name:     "unconvertible: templated format string with a literal interpolation with a verb",
+			expr:     `format("${"%s"}-%s", var.hoi, var.dag)`,
+			expected: `format("${"%s"}-%s", var.hoi, var.dag)`,
+		},
+		{
+			name:     "templated format string: directives are left untouched",
+			expr:     `format("%{if var.enabled}%s%{endif}", var.hoi)`,
+			expected: `format("%{if var.enabled}%s%{endif}", var.hoi)`,
+		},
+		{
+			name:     "heredoc format string",
+			expr:     "format(<<EOT\necho %s\n\"%s\"\nEOT\n, var.hoi, \"$${dag}\")",
+			expected: "<<EOT\necho ${var.hoi}\n\"$${dag}\"\nEOT\n",
+		},
+		{
+			name:     "heredoc format string: indentation is kept",
+			expr:     "format(<<-EOT\n    echo %s\n      %s\n\n    EOT\n, var.hoi, var.dag)",
+			expected: "<<-EOT\n    echo ${var.hoi}\n      ${var.dag}\n\n    EOT\n",
+		},
+		{
+			name:     "heredoc format string: followed by other items",
+			expr:     "[format(<<EOT\n%s\nEOT\n, var.hoi), \"dag\"]",
+			expected: "[<<EOT\n${var.hoi}\nEOT\n, \"dag\"]",
+		},
+		{
+			name:     "interpolation-only argument: unwrapped",
+			expr:     `format("%s-x", "${var.hoi}")`,
+			expected: `"${var.hoi}-x"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			expr, diags := hclsyntax.ParseExpression([]byte(tc.expr), "dummy.tf", hcl.Pos{Line: 1, Column: 1})
+			if diags.HasErrors() {
+				t.Fatalf("expression '%s' is not valid HCL: diagnostics: %v", tc.expr, diags)
+			}
+
+			result := convertFormatToInterpolation(expr, []byte(tc.expr))
+			if result != tc.expected {
+				t.Errorf("convertFormatToInterpolation(%s) = %s; want %s", tc.expr, result, tc.expected)
+			}
+		})
+	}
+}
+
+func TestConvertFormatToInterpolationPreservesValue(t *testing.T) {
+	useFormatTestModule(t)
+	testCases := []struct {
+		name string
+		expr string
+	}{
+		{
+			name: "literal dollar right before interpolation",
+			expr: `format("$%s", var.hoi)`,
+		},
+		{
+			name: "literal args that form an interpolation together",
+			expr: `format("%s%s", "$", "{var.hoi}")`,
+		},
+		{
+			name: "format string with literal dollar and arg with brace",
+			expr: `format("%s$%s", var.hoi, "{var.hoi}")`,
+		},
+		{
+			name: "escaped interpolation in literal arg",
+			expr: `format("%s-%s", "$${var.hoi}", var.hoi)`,
+		},
+		{
+			name: "escaped directive in literal arg",
+			expr: `format("%s", "%%{if true}")`,
+		},
+		{
+			name: "percent sign before literal arg with brace",
+			expr: `format("%%%s", "{hoi}")`,
+		},
+		{
+			name: "quotes, backslashes and newlines",
+			expr: `format("\"%s\"\n", "a\\b\tc")`,
+		},
+		{
+			name: "unicode escapes",
+			expr: `format("%s\u00e9", "\u0001")`,
+		},
+		{
+			name: "heredoc arg",
+			expr: "format(\"%s-%s\", var.hoi, <<EOT\n$${hoi}\n\"dag\"\nEOT\n)",
+		},
+		{
+			name: "heredoc format string",
+			expr: "format(<<EOT\n$%s\n%s\nEOT\n, var.hoi, \"$${x}\")",
+		},
+		{
+			name: "indented heredoc format string",
+			expr: "format(<<-EOT\n    a %s\n\n      %s\n    EOT\n, var.hoi, \"x\\ny\")",
+		},
+		{
+			name: "indented heredoc format string, where the argument changes the indentation",
+			expr: "format(<<-EOT\n  %sa\n  EOT\n, \" \")",
+		},
+		{
+			name: "templated format string",
+			expr: `format("%s-${"b"}-%s", "a", var.hoi)`,
+		},
+		{
+			name: "nested format call with literal dollar",
+			expr: `format("$%s", format("%s{hoi}", "$"))`,
+		},
+		{
+			name: "template arg with escapes",
+			expr: `format("%s$", "$${x}${var.hoi}\"")`,
+		},
+		{
+			name: "nested call with commas",
+			expr: `format("%s-%s", join(",", ["a", var.hoi]), var.hoi)`,
+		},
+		{
+			name: "numbers and bools",
+			expr: `format("%s%%-%t-%d-%v-%v", var.pct, var.enabled, 3, 12.5, false)`,
+		},
+	}
+
+	ctx := &hcl.EvalContext{
+		Variables: map[string]cty.Value{
+			"var": cty.ObjectVal(map[string]cty.Value{
+				"hoi":     cty.StringVal("dag"),
+				"pct":     cty.NumberIntVal(50),
+				"enabled": cty.True,
+			}),
+		},
+		Functions: map[string]function.Function{
+			"format": stdlib.FormatFunc,
+			"join":   stdlib.JoinFunc,
 		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			tokens, diags := hclsyntax.LexConfig([]byte(tc.expr), "dummy.tf", hcl.Pos{Line: 1, Column: 1})
-			if len(diags) > 0 {
-				t.Errorf("TOKENS: expression '%s' is not valid HCL: diagnostics: %v", tc.expr, diags)
-				return
+			expr, diags := hclsyntax.ParseExpression([]byte(tc.expr), "dummy.tf", hcl.Pos{Line: 1, Column: 1})
+			if diags.HasErrors() {
+				t.Fatalf("expression '%s' is not valid HCL: diagnostics: %v", tc.expr, diags)
+			}
+
+			result := convertFormatToInterpolation(expr, []byte(tc.expr))
+			if strings.Contains(result, "format(") {
+				t.Fatalf("convertFormatToInterpolation(%s) = %s; was not converted", tc.expr, result)
 			}
 
-			result := convertFormatToInterpolation(tokens)
-			resultString := string(result.Bytes())
-			if resultString != tc.expected {
-				t.Errorf("convertFormatToInterpolation(%s) = %s; want %s", tc.expr, resultString, tc.expected)
+			expected := evaluateExpression(t, tc.expr, ctx)
+			actual := evaluateExpression(t, result, ctx)
+			if !expected.RawEquals(actual) {
+				t.Errorf("convertFormatToInterpolation(%s) = %s, evaluates to %#v; want %#v", tc.expr, result, actual, expected)
 			}
 		})
 	}
 }
 
+func evaluateExpression(t *testing.T, expr string, ctx *hcl.EvalContext) cty.Value {
+	parsed, diags := hclsyntax.ParseExpression([]byte(expr), "dummy.tf", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		t.Fatalf("expression '%s' is not valid HCL: diagnostics: %v", expr, diags)
+	}
+
+	val, diags := parsed.Value(ctx)
+	if diags.HasErrors() {
+		t.Fatalf("expression '%s' cannot be evaluated: diagnostics: %v", expr, diags)
+	}
+	return val
+}
+
+func TestParseFormatVerbs(t *testing.T) {
+	testCases := []struct {
+		name          string
+		fmtString     string
+		expectedVerbs []fmtVerb
+		expectedErr   bool
+	}{
+		{
+			name:          "no verbs",
+			fmtString:     "hoi 100%%",
+			expectedVerbs: nil,
+		},
+		{
+			name:      "flags, width and precision",
+			fmtString: "%-8.3f %+d",
+			expectedVerbs: []fmtVerb{
+				{raw: "%-8.3f", flags: "-", width: "8", precision: ".3", verb: "f", argIndex: 0},
+				{raw: "%+d", flags: "+", verb: "d", argIndex: 1},
+			},
+		},
+		{
+			name:      "explicit argument index continues with next argument",
+			fmtString: "%[3]s %s %#v",
+			expectedVerbs: []fmtVerb{
+				{raw: "%[3]s", verb: "s", argIndex: 2},
+				{raw: "%s", verb: "s", argIndex: 3},
+				{raw: "%#v", verb: "#v", argIndex: 4},
+			},
+		},
+		{
+			name:        "invalid verb",
+			fmtString:   "%y",
+			expectedErr: true,
+		},
+		{
+			name:        "unterminated verb",
+			fmtString:   "hoi %",
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			segments, err := parseFormatVerbs(tc.fmtString)
+			if (err != nil) != tc.expectedErr {
+				t.Fatalf("parseFormatVerbs(%s) error = %v; want error: %t", tc.fmtString, err, tc.expectedErr)
+			}
+
+			var verbs []fmtVerb
+			for _, seg := range segments {
+				if seg.verb != nil {
+					verbs = append(verbs, *seg.verb)
+				}
+			}
+			if !reflect.DeepEqual(verbs, tc.expectedVerbs) {
+				t.Errorf("parseFormatVerbs(%s) = %+v; want %+v", tc.fmtString, verbs, tc.expectedVerbs)
+			}
+		})
+	}
+}
+
+func TestVerbCheckConvertible(t *testing.T) {
+	stringArg := fmtArg{ty: cty.String}
+	boolArg := fmtArg{ty: cty.Bool}
+	numberArg := fmtArg{ty: cty.Number}
+	integerArg := fmtArg{ty: cty.Number, integer: true}
+	testCases := []struct {
+		verb        string
+		arg         fmtArg
+		convertible bool
+	}{
+		{verb: "%s", arg: stringArg, convertible: true},
+		{verb: "%s", arg: numberArg, convertible: true},
+		{verb: "%s", arg: boolArg, convertible: true},
+		{verb: "%s", arg: unknownFmtArg, convertible: false},
+		{verb: "%v", arg: stringArg, convertible: true},
+		{verb: "%v", arg: boolArg, convertible: true},
+		{verb: "%v", arg: numberArg, convertible: false},
+		{verb: "%v", arg: fmtArg{ty: cty.Number, value: cty.NumberIntVal(42), integer: true}, convertible: true},
+		{verb: "%v", arg: fmtArg{ty: cty.Number, value: cty.NumberFloatVal(0.5)}, convertible: true},
+		{verb: "%v", arg: fmtArg{ty: cty.Number, value: cty.NumberIntVal(1000000), integer: true}, convertible: false},
+		{verb: "%v", arg: unknownFmtArg, convertible: false},
+		{verb: "%d", arg: integerArg, convertible: true},
+		{verb: "%d", arg: numberArg, convertible: false},
+		{verb: "%d", arg: stringArg, convertible: false},
+		{verb: "%t", arg: boolArg, convertible: true},
+		{verb: "%t", arg: stringArg, convertible: false},
+		{verb: "%5s", arg: stringArg, convertible: false},
+		{verb: "%q", arg: stringArg, convertible: false},
+	}
+
+	for _, tc := range testCases {
+		segments, err := parseFormatVerbs(tc.verb)
+		if err != nil {
+			t.Fatalf("parseFormatVerbs(%s) error = %v", tc.verb, err)
+		}
+
+		err = segments[0].verb.checkConvertible(tc.arg)
+		if (err == nil) != tc.convertible {
+			t.Errorf("checkConvertible(%s, %+v) error = %v; want convertible %t", tc.verb, tc.arg, err, tc.convertible)
+		}
+	}
+}
+
 func TestGetAttributeForWrite(t *testing.T) {
 	testCases := []struct {
 		name                  string
@@ -95,7 +575,7 @@ func TestGetAttributeForWrite(t *testing.T) {
 			block2 {
 				hoi = "dag"
 			}`,
-			address:               hclAddress{[]hclBlockId{{"block2", nil}}, "hoi"},
+			address:               hclAddress{[]hclBlockId{{typeName: "block2"}}, "hoi"},
 			expectedAttributeName: "hoi",
 		},
 		{
@@ -107,7 +587,69 @@ func TestGetAttributeForWrite(t *testing.T) {
 			block "id3" "id4" {
 				hoi = "dag"
 			}`,
-			address:               hclAddress{[]hclBlockId{{"block", []string{"id3", "id4"}}}, "hoi"},
+			address:               hclAddress{[]hclBlockId{{typeName: "block", labels: []string{"id3", "id4"}}}, "hoi"},
+			expectedAttributeName: "hoi",
+		},
+		{
+			name: "nested block with the same type as a top-level block",
+			hcl: `ingress {
+				hoi = "other"
+			}
+
+			resource "aws_security_group" "sg" {
+				ingress {
+					hoi = "dag"
+				}
+			}`,
+			address: hclAddress{[]hclBlockId{
+				{typeName: "resource", labels: []string{"aws_security_group", "sg"}},
+				{typeName: "ingress"},
+			}, "hoi"},
+			expectedAttributeName: "hoi",
+		},
+		{
+			name: "repeated unlabeled nested blocks",
+			hcl: `resource "aws_security_group" "sg" {
+				ingress {
+					hoi = "other"
+				}
+				ingress {
+					hoi = "dag"
+				}
+			}`,
+			address: hclAddress{[]hclBlockId{
+				{typeName: "resource", labels: []string{"aws_security_group", "sg"}},
+				{typeName: "ingress", index: 1},
+			}, "hoi"},
+			expectedAttributeName: "hoi",
+		},
+		{
+			name: "dynamic content block",
+			hcl: `resource "aws_security_group" "sg" {
+				dynamic "ingress" {
+					for_each = var.ports
+					content {
+						hoi = "dag"
+					}
+				}
+			}`,
+			address: hclAddress{[]hclBlockId{
+				{typeName: "resource", labels: []string{"aws_security_group", "sg"}},
+				{typeName: "dynamic", labels: []string{"ingress"}},
+				{typeName: "content"},
+			}, "hoi"},
+			expectedAttributeName: "hoi",
+		},
+		{
+			name: "repeated locals blocks",
+			hcl: `locals {
+				hoi = "other"
+			}
+
+			locals {
+				hoi = "dag"
+			}`,
+			address:               hclAddress{[]hclBlockId{{typeName: "locals", index: 1}}, "hoi"},
 			expectedAttributeName: "hoi",
 		},
 	}
@@ -117,6 +659,12 @@ func TestGetAttributeForWrite(t *testing.T) {
 		body, resultAttrName := getAttributeForWrite(hclFile, tc.address)
 		if resultAttrName != tc.expectedAttributeName || body == nil || body.GetAttribute(resultAttrName) == nil {
 			t.Errorf("getAttributeForWrite(%s) = %v, %s; want %s", tc.hcl, body, resultAttrName, tc.expectedAttributeName)
+			continue
+		}
+
+		value := strings.TrimSpace(string(body.GetAttribute(resultAttrName).Expr().BuildTokens(nil).Bytes()))
+		if value != `"dag"` {
+			t.Errorf("getAttributeForWrite(%s) returned the attribute of the wrong block, with value %s", tc.hcl, value)
 		}
 	}
 }
@@ -179,3 +727,28 @@ func TestEqualsLabels(t *testing.T) {
 		}
 	}
 }
+
+func TestCheckFormatUsagesSkipsUnparseableFiles(t *testing.T) {
+	dir := t.TempDir()
+	chdirForTest(t, dir)
+	good := filepath.Join(dir, "good.tf")
+	bad := filepath.Join(dir, "bad.tf")
+	writeTestFiles(t, dir, map[string]string{
+		"good.tf": "variable \"a\" {\n  type = string\n}\n\nlocals {\n  a = format(\"%s-x\", var.a)\n  b = format(\"%05d\", var.b)\n}\n",
+		"bad.tf":  "locals {\n  a = format(\"%s\",\n",
+	})
+
+	violations, err := checkFormatUsages([]string{bad, good})
+	if err != nil {
+		t.Fatalf("checkFormatUsages() error = %v; want the unparseable file to be skipped", err)
+	}
+	if len(violations) != 2 {
+		t.Fatalf("checkFormatUsages() = %d violations; want 2", len(violations))
+	}
+	if violations[0].replacement == nil || *violations[0].replacement != `"${var.a}-x"` {
+		t.Errorf("checkFormatUsages() first violation has replacement %v; want the converted template", violations[0].replacement)
+	}
+	if violations[1].replacement != nil || violations[1].note == "" {
+		t.Errorf("checkFormatUsages() second violation = %+v; want a note why it cannot be fixed", violations[1])
+	}
+}
@@ -0,0 +1,302 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// fmtVerb is a single verb in a format string, see
+// https://developer.hashicorp.com/terraform/language/functions/format#specification-syntax
+type fmtVerb struct {
+	raw       string
+	flags     string
+	width     string
+	precision string // including the leading dot, if any
+	verb      string
+	argIndex  int // zero-based index of the argument that the verb consumes
+}
+
+// fmtSegment is either a literal part of the format string, a verb, or an interpolation when the format string is a
+// template itself
+type fmtSegment struct {
+	literal string
+	verb    *fmtVerb
+	expr    string
+}
+
+const fmtVerbChars = "vtbdoxXeEfgGsq"
+
+// parseFormatVerbs splits the format string into literals and verbs, where "%%" is returned as literal "%"
+func parseFormatVerbs(fmtString string) ([]fmtSegment, error) {
+	segments, _, err := parseFormatVerbsFrom(fmtString, 0)
+	return segments, err
+}
+
+// parseFormatVerbsFrom splits the literal part of a format string into literals and verbs, where the verbs consume the
+// arguments from nextArg on, and returns the argument that is consumed next after this part
+func parseFormatVerbsFrom(fmtString string, nextArg int) ([]fmtSegment, int, error) {
+	var segments []fmtSegment
+	var literal strings.Builder
+
+	for i := 0; i < len(fmtString); i++ {
+		if fmtString[i] != '%' {
+			literal.WriteByte(fmtString[i])
+			continue
+		}
+
+		if i+1 < len(fmtString) && fmtString[i+1] == '%' {
+			literal.WriteByte('%')
+			i++
+			continue
+		}
+
+		verb, length, err := parseFormatVerb(fmtString[i:], nextArg)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if literal.Len() > 0 {
+			segments = append(segments, fmtSegment{literal: literal.String()})
+			literal.Reset()
+		}
+		segments = append(segments, fmtSegment{verb: verb})
+
+		nextArg = verb.argIndex + 1
+		i += length - 1 // account for the next loop increase
+	}
+
+	if literal.Len() > 0 {
+		segments = append(segments, fmtSegment{literal: literal.String()})
+	}
+
+	return segments, nextArg, nil
+}
+
+// parseFormatVerb parses the verb at the start of s, and returns it with its length in bytes
+func parseFormatVerb(s string, nextArg int) (*fmtVerb, int, error) {
+	v := &fmtVerb{argIndex: nextArg}
+
+	i := 1 // skip the %
+	start := i
+	for i < len(s) && strings.IndexByte(" +-#0", s[i]) != -1 {
+		i++
+	}
+	v.flags = s[start:i]
+
+	if i < len(s) && s[i] == '[' {
+		end := strings.IndexByte(s[i:], ']')
+		if end == -1 {
+			return nil, 0, fmt.Errorf("unterminated argument index in verb '%v'", s)
+		}
+		n, err := strconv.Atoi(s[i+1 : i+end])
+		if err != nil || n < 1 {
+			return nil, 0, fmt.Errorf("invalid argument index in verb '%v'", s[:i+end+1])
+		}
+		v.argIndex = n - 1
+		i += end + 1
+	}
+
+	start = i
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	v.width = s[start:i]
+
+	if i < len(s) && s[i] == '.' {
+		start = i
+		i++
+		for i < len(s) && isDigit(s[i]) {
+			i++
+		}
+		v.precision = s[start:i]
+	}
+
+	if i >= len(s) || strings.IndexByte(fmtVerbChars, s[i]) == -1 {
+		return nil, 0, fmt.Errorf("invalid verb '%v'", s[:min(i+1, len(s))])
+	}
+	if s[i] == 'v' && v.flags == "#" {
+		v.flags = ""
+		v.verb = "#v"
+	} else {
+		v.verb = string(s[i])
+	}
+	i++
+
+	v.raw = s[:i]
+	return v, i, nil
+}
+
+// fmtArg is what is known about the value of a format argument before running terraform
+type fmtArg struct {
+	// ty is the primitive type of the value, or cty.DynamicPseudoType when it is unknown or not primitive
+	ty cty.Type
+
+	// value is the value of a literal argument, or cty.NilVal
+	value cty.Value
+
+	// integer tells whether the value is known to be a whole number
+	integer bool
+}
+
+var unknownFmtArg = fmtArg{ty: cty.DynamicPseudoType}
+
+// functions that are known to return a value of a primitive type
+var stringFunctions = []string{"abspath", "base64decode", "base64encode", "basename", "chomp", "dirname", "file", "format", "formatdate", "indent", "join", "jsonencode", "lower", "md5", "pathexpand", "replace", "sha1", "sha256", "sha512", "strrev", "substr", "templatefile", "timestamp", "title", "trim", "trimprefix", "trimspace", "trimsuffix", "upper", "urlencode", "uuid", "yamlencode"}
+var integerFunctions = []string{"ceil", "floor", "index", "length", "parseint", "signum"}
+var numberFunctions = []string{"abs", "log", "max", "min", "pow", "tonumber"}
+var boolFunctions = []string{"alltrue", "anytrue", "can", "contains", "endswith", "fileexists", "startswith", "tobool"}
+
+// getFmtArg determines what is known about the value of the expression, using the declarations of the variables and
+// locals of its module
+func getFmtArg(expr hclsyntax.Expression, decls moduleDeclarations, depth int) fmtArg {
+	switch e := expr.(type) {
+	case *hclsyntax.LiteralValueExpr:
+		if e.Val.IsNull() || !e.Val.Type().IsPrimitiveType() {
+			return unknownFmtArg
+		}
+		return fmtArg{ty: e.Val.Type(), value: e.Val, integer: e.Val.Type() == cty.Number && e.Val.AsBigFloat().IsInt()}
+	case *hclsyntax.TemplateExpr:
+		if literal, ok := stringLiteralValue(e); ok {
+			return fmtArg{ty: cty.String, value: cty.StringVal(literal)}
+		}
+		return fmtArg{ty: cty.String}
+	case *hclsyntax.TemplateWrapExpr:
+		return getFmtArg(e.Wrapped, decls, depth)
+	case *hclsyntax.ParenthesesExpr:
+		return getFmtArg(e.Expression, decls, depth)
+	case *hclsyntax.ConditionalExpr:
+		trueArg := getFmtArg(e.TrueResult, decls, depth)
+		falseArg := getFmtArg(e.FalseResult, decls, depth)
+		if trueArg.ty.Equals(falseArg.ty) {
+			return fmtArg{ty: trueArg.ty, integer: trueArg.integer && falseArg.integer}
+		}
+	case *hclsyntax.BinaryOpExpr:
+		return fmtArg{ty: e.Op.Type}
+	case *hclsyntax.UnaryOpExpr:
+		return fmtArg{ty: e.Op.Type}
+	case *hclsyntax.FunctionCallExpr:
+		switch {
+		case contains(stringFunctions, e.Name):
+			return fmtArg{ty: cty.String}
+		case contains(integerFunctions, e.Name):
+			return fmtArg{ty: cty.Number, integer: true}
+		case contains(numberFunctions, e.Name):
+			return fmtArg{ty: cty.Number}
+		case contains(boolFunctions, e.Name):
+			return fmtArg{ty: cty.Bool}
+		}
+	case *hclsyntax.ScopeTraversalExpr:
+		if len(e.Traversal) != 2 {
+			break
+		}
+		attr, ok := e.Traversal[1].(hcl.TraverseAttr)
+		if !ok {
+			break
+		}
+
+		switch e.Traversal.RootName() {
+		case "var":
+			if typeExpr, exists := decls.variableTypes[attr.Name]; exists {
+				if ty, diags := typeexpr.TypeConstraint(typeExpr); !diags.HasErrors() && ty.IsPrimitiveType() {
+					return fmtArg{ty: ty}
+				}
+			}
+		case "local":
+			// guard against locals that refer to each other
+			if localExpr, exists := decls.locals[attr.Name]; exists && depth < 10 {
+				return getFmtArg(localExpr, decls, depth+1)
+			}
+		case "count":
+			return fmtArg{ty: cty.Number, integer: true}
+		case "each", "path", "terraform":
+			if attr.Name != "value" {
+				return fmtArg{ty: cty.String}
+			}
+		}
+	}
+
+	return unknownFmtArg
+}
+
+// checkConvertibleFormat returns why the format string with its arguments cannot be converted to a template, if so
+func checkConvertibleFormat(segments []fmtSegment, args []fmtArg) error {
+	usedArgs := make([]bool, len(args))
+	for _, seg := range segments {
+		if seg.verb == nil {
+			continue
+		}
+
+		if seg.verb.argIndex >= len(args) {
+			return fmt.Errorf("verb '%v' has no argument", seg.verb.raw)
+		}
+		if err := seg.verb.checkConvertible(args[seg.verb.argIndex]); err != nil {
+			return err
+		}
+		usedArgs[seg.verb.argIndex] = true
+	}
+
+	for i, used := range usedArgs {
+		if !used {
+			return fmt.Errorf("argument %d is not used by the format string", i+1)
+		}
+	}
+
+	// a template with a single interpolation evaluates to the value itself, instead of converting it to a string
+	if len(segments) == 1 && segments[0].verb != nil && args[segments[0].verb.argIndex].ty != cty.String {
+		return fmt.Errorf("argument %d is not known to be a string, while the template would return it as is", segments[0].verb.argIndex+1)
+	}
+
+	return nil
+}
+
+// checkConvertible returns why the verb cannot be written as a template interpolation of the argument, if so, as a
+// template converts its interpolations to a string, which is only equivalent to the verb for some types of arguments
+func (v *fmtVerb) checkConvertible(arg fmtArg) error {
+	if v.flags != "" || v.width != "" || v.precision != "" {
+		return fmt.Errorf("verb '%v' uses flags, width or precision, which have no equivalent in a template", v.raw)
+	}
+
+	switch v.verb {
+	case "s":
+		if !arg.ty.IsPrimitiveType() {
+			return fmt.Errorf("verb '%v' requires a string, number or bool, while the type of argument %d is unknown", v.raw, v.argIndex+1)
+		}
+	case "t":
+		if arg.ty != cty.Bool {
+			return fmt.Errorf("verb '%v' requires a bool, while argument %d is not known to be one", v.raw, v.argIndex+1)
+		}
+	case "d":
+		if !arg.integer {
+			return fmt.Errorf("verb '%v' requires a whole number, while argument %d is not known to be one", v.raw, v.argIndex+1)
+		}
+	case "v":
+		// numbers are formatted in scientific notation from 1e+06 on, and collections as JSON
+		if arg.ty != cty.String && arg.ty != cty.Bool && !isPlainNumberLiteral(arg) {
+			return fmt.Errorf("verb '%v' formats numbers and collections differently than a template interpolation does, and argument %d is not known to be a string or bool", v.raw, v.argIndex+1)
+		}
+	default:
+		return fmt.Errorf("verb '%v' formats its argument differently than a template interpolation does", v.raw)
+	}
+	return nil
+}
+
+// isPlainNumberLiteral returns whether the argument is a literal number, that %v formats the same as a template does
+func isPlainNumberLiteral(arg fmtArg) bool {
+	if arg.ty != cty.Number || arg.value == cty.NilVal {
+		return false
+	}
+
+	str, err := convert.Convert(arg.value, cty.String)
+	return err == nil && str.AsString() == arg.value.AsBigFloat().Text('g', -1)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
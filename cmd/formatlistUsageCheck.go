@@ -0,0 +1,416 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+type argKind int
+
+const (
+	argUnknown argKind = iota
+	argScalar
+	argList
+)
+
+type formatlistInvocation struct {
+	call       *hclsyntax.FunctionCallExpr
+	attr       *hclsyntax.Attribute
+	hclAddress hclAddress
+
+	// replacement is the for expression that replaces the call, unless conversionErr explains why it cannot be converted
+	replacement   string
+	conversionErr error
+}
+
+// functions that are known to return a list, or a single value
+var listFunctions = []string{"compact", "concat", "distinct", "flatten", "formatlist", "keys", "range", "reverse", "slice", "sort", "split", "tolist", "values"}
+var scalarFunctions = []string{"abspath", "basename", "format", "join", "length", "lower", "replace", "substr", "title", "tonumber", "tostring", "trimspace", "upper"}
+
+func init() {
+	registerRule(rule{
+		id:          "formatlist-usage",
+		name:        "formatlist() usages",
+		description: "Usages of formatlist() that can be written as a for expression",
+		severity:    severityWarning,
+		check:       checkFormatlistUsages,
+		fix:         performFormatlistUsageFix,
+	})
+}
+
+// CHECK
+
+func checkFormatlistUsages(tfFiles []string) ([]violation, error) {
+	var violations []violation
+	for _, f := range tfFiles {
+		invocations, err := checkForFormatlistUsageInFile(f)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, invoke := range invocations {
+			v := violation{
+				group:   fmt.Sprintf("file '%v'", f),
+				address: invoke.hclAddress,
+				rng:     invoke.call.Range(),
+				text:    sourceText(invoke.call.Range()),
+			}
+			if invoke.conversionErr != nil {
+				v.note = "cannot be fixed: " + invoke.conversionErr.Error()
+			} else {
+				v.replacement = &invoke.replacement
+			}
+			violations = append(violations, v)
+		}
+	}
+
+	return violations, nil
+}
+
+func checkForFormatlistUsageInFile(filename string) ([]formatlistInvocation, error) {
+	hclFile := parseHclFileOrSkip(filename)
+	if hclFile == nil {
+		return nil, nil
+	}
+
+	var result []formatlistInvocation
+	walkAttributes(hclFile.Body.(*hclsyntax.Body), nil, func(attr *hclsyntax.Attribute, address hclAddress) {
+		for _, call := range findFunctionCalls(attr.Expr, "formatlist") {
+			replacement, conversionErr := convertFormatlistToFor(call, hclFile.Bytes)
+			result = append(result, formatlistInvocation{call, attr, address, replacement, conversionErr})
+		}
+	})
+
+	return result, nil
+}
+
+// convertFormatlistToFor converts the formatlist() call to an equivalent for expression, that iterates over the first
+// list argument, and indexes the other list arguments
+func convertFormatlistToFor(call *hclsyntax.FunctionCallExpr, src []byte) (string, error) {
+	if call.ExpandFinal {
+		return "", errors.New("arguments that are expanded with '...' are not supported")
+	}
+	if len(call.Args) == 0 {
+		return "", errors.New("format string is missing")
+	}
+
+	fmtString, ok := stringLiteralValue(call.Args[0])
+	if !ok {
+		return "", errors.New("format string is not a literal string")
+	}
+
+	segments, err := parseFormatVerbs(fmtString)
+	if err != nil {
+		return "", err
+	}
+
+	decls, err := getModuleDeclarations(path.Dir(call.Range().Filename))
+	if err != nil {
+		return "", fmt.Errorf("cannot determine which arguments are lists: %s", err)
+	}
+
+	args := call.Args[1:]
+	kinds, err := inferListArgs(classifyArgs(args, decls))
+	if err != nil {
+		return "", err
+	}
+
+	// the verbs format the elements of the list arguments
+	var fmtArgs []fmtArg
+	for i, arg := range args {
+		if kinds[i] == argList {
+			fmtArgs = append(fmtArgs, getElementFmtArg(arg, decls, 0))
+		} else {
+			fmtArgs = append(fmtArgs, getFmtArg(arg, decls, 0))
+		}
+	}
+	if err = checkConvertibleFormat(segments, fmtArgs); err != nil {
+		return "", err
+	}
+
+	var listArgs []int
+	for i, kind := range kinds {
+		if kind == argList {
+			listArgs = append(listArgs, i)
+		}
+	}
+	if err = checkEqualListLengths(args, listArgs); err != nil {
+		return "", err
+	}
+
+	usedNames := getRootNames(args)
+	valueName := pickName(usedNames, "v", "value", "item")
+	indexName := pickName(usedNames, "i", "idx", "index")
+
+	var parts []templatePart
+	for _, seg := range segments {
+		if seg.verb == nil {
+			parts = append(parts, templatePart{literal: seg.literal})
+			continue
+		}
+
+		arg := args[seg.verb.argIndex]
+		argSrc := string(arg.Range().SliceBytes(src))
+		switch {
+		case seg.verb.argIndex == listArgs[0]:
+			parts = append(parts, templatePart{expr: valueName})
+		case kinds[seg.verb.argIndex] == argList:
+			parts = append(parts, templatePart{expr: parenthesize(arg, argSrc) + "[" + indexName + "]"})
+		default:
+			if literal, ok := stringLiteralValue(arg); ok {
+				parts = append(parts, templatePart{literal: literal})
+			} else {
+				parts = append(parts, templatePart{expr: argSrc})
+			}
+		}
+	}
+
+	iterator := valueName
+	if len(listArgs) > 1 {
+		iterator = indexName + ", " + valueName
+	}
+	list := string(args[listArgs[0]].Range().SliceBytes(src))
+
+	return fmt.Sprintf(`[for %v in %v : %v]`, iterator, list, buildQuotedTemplate(parts)), nil
+}
+
+// checkEqualListLengths returns why the list arguments might not have the same length, if so, as formatlist() fails
+// then, while the for expression would ignore the extra elements of the other lists
+func checkEqualListLengths(args []hclsyntax.Expression, listArgs []int) error {
+	if len(listArgs) < 2 {
+		return nil
+	}
+
+	length := -1
+	for _, i := range listArgs {
+		tuple, ok := args[i].(*hclsyntax.TupleConsExpr)
+		if !ok {
+			return fmt.Errorf("cannot determine whether the lists have the same length, as the length of argument %d is unknown", i+1)
+		}
+		if length != -1 && len(tuple.Exprs) != length {
+			return errors.New("the lists have different lengths, so formatlist() fails")
+		}
+		length = len(tuple.Exprs)
+	}
+	return nil
+}
+
+// inferListArgs determines which of the unknown arguments are lists, which is only possible when there is a single
+// unknown argument, and no other list arguments, as formatlist() requires at least one list
+func inferListArgs(kinds []argKind) ([]argKind, error) {
+	var unknownArgs []int
+	hasList := false
+	for i, kind := range kinds {
+		if kind == argUnknown {
+			unknownArgs = append(unknownArgs, i)
+		}
+		if kind == argList {
+			hasList = true
+		}
+	}
+
+	if len(unknownArgs) == 0 {
+		if !hasList {
+			return nil, errors.New("none of the arguments is a list")
+		}
+		return kinds, nil
+	}
+
+	if len(unknownArgs) > 1 || hasList {
+		return nil, fmt.Errorf("cannot determine whether argument %d is a list or a single value", unknownArgs[0]+1)
+	}
+
+	result := append([]argKind(nil), kinds...)
+	result[unknownArgs[0]] = argList
+	return result, nil
+}
+
+// classifyArgs determines for each argument whether it is a list or a single value, using the declarations of
+// variables and locals in the module
+func classifyArgs(args []hclsyntax.Expression, decls moduleDeclarations) []argKind {
+	var kinds []argKind
+	for _, arg := range args {
+		kinds = append(kinds, classifyArg(arg, decls, 0))
+	}
+	return kinds
+}
+
+func classifyArg(expr hclsyntax.Expression, decls moduleDeclarations, depth int) argKind {
+	switch e := expr.(type) {
+	case *hclsyntax.TupleConsExpr:
+		return argList
+	case *hclsyntax.ForExpr:
+		if e.KeyExpr == nil {
+			return argList
+		}
+	case *hclsyntax.TemplateExpr, *hclsyntax.TemplateWrapExpr, *hclsyntax.LiteralValueExpr:
+		return argScalar
+	case *hclsyntax.FunctionCallExpr:
+		if contains(listFunctions, e.Name) {
+			return argList
+		}
+		if contains(scalarFunctions, e.Name) {
+			return argScalar
+		}
+	case *hclsyntax.ScopeTraversalExpr:
+		if len(e.Traversal) != 2 {
+			break
+		}
+		attr, ok := e.Traversal[1].(hcl.TraverseAttr)
+		if !ok {
+			break
+		}
+
+		switch e.Traversal.RootName() {
+		case "var":
+			if typeExpr, exists := decls.variableTypes[attr.Name]; exists {
+				return classifyTypeConstraint(typeExpr)
+			}
+		case "local":
+			// guard against locals that refer to each other
+			if localExpr, exists := decls.locals[attr.Name]; exists && depth < 10 {
+				return classifyArg(localExpr, decls, depth+1)
+			}
+		case "count", "path", "terraform":
+			return argScalar
+		}
+	}
+
+	return argUnknown
+}
+
+func classifyTypeConstraint(typeExpr hclsyntax.Expression) argKind {
+	switch e := typeExpr.(type) {
+	case *hclsyntax.ScopeTraversalExpr:
+		if contains([]string{"string", "number", "bool"}, e.Traversal.RootName()) {
+			return argScalar
+		}
+	case *hclsyntax.FunctionCallExpr:
+		if e.Name == "list" || e.Name == "tuple" {
+			return argList
+		}
+	}
+	return argUnknown
+}
+
+// getElementFmtArg determines what is known about the elements of the list argument, which are all of the same
+// primitive type when it is known
+func getElementFmtArg(expr hclsyntax.Expression, decls moduleDeclarations, depth int) fmtArg {
+	switch e := expr.(type) {
+	case *hclsyntax.TupleConsExpr:
+		if len(e.Exprs) == 0 {
+			break
+		}
+		elem := getFmtArg(e.Exprs[0], decls, depth)
+		for _, elemExpr := range e.Exprs[1:] {
+			other := getFmtArg(elemExpr, decls, depth)
+			if !other.ty.Equals(elem.ty) {
+				return unknownFmtArg
+			}
+			elem.integer = elem.integer && other.integer
+		}
+		return fmtArg{ty: elem.ty, integer: elem.integer}
+	case *hclsyntax.FunctionCallExpr:
+		switch e.Name {
+		case "formatlist", "keys", "split":
+			return fmtArg{ty: cty.String}
+		case "range":
+			return fmtArg{ty: cty.Number, integer: true}
+		}
+	case *hclsyntax.ScopeTraversalExpr:
+		if len(e.Traversal) != 2 {
+			break
+		}
+		attr, ok := e.Traversal[1].(hcl.TraverseAttr)
+		if !ok {
+			break
+		}
+
+		switch e.Traversal.RootName() {
+		case "var":
+			if typeExpr, exists := decls.variableTypes[attr.Name]; exists {
+				ty, diags := typeexpr.TypeConstraint(typeExpr)
+				if !diags.HasErrors() && ty.IsListType() && ty.ElementType().IsPrimitiveType() {
+					return fmtArg{ty: ty.ElementType()}
+				}
+			}
+		case "local":
+			// guard against locals that refer to each other
+			if localExpr, exists := decls.locals[attr.Name]; exists && depth < 10 {
+				return getElementFmtArg(localExpr, decls, depth+1)
+			}
+		}
+	}
+
+	return unknownFmtArg
+}
+
+func parenthesize(expr hclsyntax.Expression, exprSrc string) string {
+	switch expr.(type) {
+	case *hclsyntax.ScopeTraversalExpr, *hclsyntax.FunctionCallExpr, *hclsyntax.TupleConsExpr, *hclsyntax.ParenthesesExpr:
+		return exprSrc
+	default:
+		return "(" + exprSrc + ")"
+	}
+}
+
+// getRootNames returns the names of the root of all references in the expressions
+func getRootNames(exprs []hclsyntax.Expression) []string {
+	var names []string
+	for _, expr := range exprs {
+		for _, traversal := range expr.Variables() {
+			names = append(names, traversal.RootName())
+		}
+	}
+	return names
+}
+
+func pickName(usedNames []string, candidates ...string) string {
+	for _, c := range candidates {
+		if !contains(usedNames, c) {
+			return c
+		}
+	}
+	return candidates[len(candidates)-1] + "_"
+}
+
+// FIX
+
+func performFormatlistUsageFix(tfFiles []string) error {
+	for _, f := range tfFiles {
+		invocations, err := checkForFormatlistUsageInFile(f)
+		if err != nil {
+			return err
+		}
+
+		var replacements []exprReplacement
+		for _, invoke := range invocations {
+			if invoke.conversionErr != nil {
+				rng := invoke.call.Range()
+				reportWarning(&rng, "formatlist() call is not converted", invoke.conversionErr.Error())
+				continue
+			}
+			replacements = append(replacements, exprReplacement{
+				address:   invoke.hclAddress,
+				exprRange: invoke.attr.Expr.Range(),
+				rng:       invoke.call.Range(),
+				text:      invoke.replacement,
+			})
+		}
+
+		if len(replacements) == 0 {
+			continue
+		}
+		if err = replaceInAttributes(f, replacements); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+)
+
+// useFormatlistTestModule makes the expressions that are parsed from "dummy.tf" part of a module, that declares the
+// variables and locals they use
+func useFormatlistTestModule(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"variables.tf": `variable "names" {
+  type = list(string)
+}
+variable "others" {
+  type = list(string)
+}
+variable "ports" {
+  type = list(number)
+}
+variable "domain" {
+  type = string
+}
+variable "untyped" {}
+
+locals {
+  zones = split(",", "a,b")
+}
+`,
+	})
+	chdirForTest(t, dir)
+}
+
+func parseFormatlistCall(t *testing.T, expr string) *hclsyntax.FunctionCallExpr {
+	t.Helper()
+	parsed, diags := hclsyntax.ParseExpression([]byte(expr), "dummy.tf", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		t.Fatalf("expression '%s' is not valid HCL: diagnostics: %v", expr, diags)
+	}
+	calls := findFunctionCalls(parsed, "formatlist")
+	if len(calls) == 0 {
+		t.Fatalf("expression '%s' has no formatlist() call", expr)
+	}
+	return calls[0]
+}
+
+func TestConvertFormatlistToFor(t *testing.T) {
+	useFormatlistTestModule(t)
+	testCases := []struct {
+		name     string
+		expr     string
+		expected string // empty when the call cannot be converted
+	}{
+		{
+			name:     "single list",
+			expr:     `formatlist("%s-x", var.names)`,
+			expected: `[for v in var.names : "${v}-x"]`,
+		},
+		{
+			name:     "list and scalar",
+			expr:     `formatlist("%s.%s", var.names, var.domain)`,
+			expected: `[for v in var.names : "${v}.${var.domain}"]`,
+		},
+		{
+			name:     "scalar before list, with literal scalar",
+			expr:     `formatlist("%s.%s", "www", local.zones)`,
+			expected: `[for v in local.zones : "www.${v}"]`,
+		},
+		{
+			name:     "lists of equal lengths",
+			expr:     `formatlist("%s=%s", ["a", "b"], ["c", "d"])`,
+			expected: `[for i, v in ["a", "b"] : "${v}=${["c", "d"][i]}"]`,
+		},
+		{
+			name:     "whole numbers",
+			expr:     `formatlist("%d-x", range(3))`,
+			expected: `[for v in range(3) : "${v}-x"]`,
+		},
+		{
+			name: "unconvertible: lists of different lengths",
+			expr: `formatlist("%s=%s", ["a", "b"], ["c"])`,
+		},
+		{
+			name: "unconvertible: lists of unknown lengths",
+			expr: `formatlist("%s=%s", var.names, var.others)`,
+		},
+		{
+			name: "unconvertible: no list",
+			expr: `formatlist("%s-x", var.domain)`,
+		},
+		{
+			name: "unconvertible: list or scalar cannot be determined",
+			expr: `formatlist("%s-%s", var.names, var.untyped)`,
+		},
+		{
+			name: "unconvertible: elements of unknown type",
+			expr: `formatlist("%s-x", var.untyped)`,
+		},
+		{
+			name: "unconvertible: %v with numbers",
+			expr: `formatlist("%v-x", var.ports)`,
+		},
+		{
+			name: "unconvertible: single verb with numbers, as the for expression would return the numbers themselves",
+			expr: `formatlist("%d", range(3))`,
+		},
+		{
+			name: "unconvertible: width",
+			expr: `formatlist("%05d", range(3))`,
+		},
+		{
+			name: "unconvertible: format string is not a literal",
+			expr: `formatlist(var.domain, var.names)`,
+		},
+		{
+			name: "unconvertible: expanded arguments",
+			expr: `formatlist("%s", var.names...)`,
+		},
+		{
+			name: "unconvertible: unused argument",
+			expr: `formatlist("%s-x", var.names, var.domain)`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			call := parseFormatlistCall(t, tc.expr)
+			result, err := convertFormatlistToFor(call, []byte(tc.expr))
+			if tc.expected == "" {
+				if err == nil {
+					t.Errorf("convertFormatlistToFor(%s) = %s; want it to be unconvertible", tc.expr, result)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("convertFormatlistToFor(%s) error = %v", tc.expr, err)
+			}
+			if result != tc.expected {
+				t.Errorf("convertFormatlistToFor(%s) = %s; want %s", tc.expr, result, tc.expected)
+			}
+		})
+	}
+}
+
+func TestConvertFormatlistToForPreservesValue(t *testing.T) {
+	useFormatlistTestModule(t)
+	testCases := []string{
+		`formatlist("%s-x", var.names)`,
+		`formatlist("%s.%s", var.names, var.domain)`,
+		`formatlist("%s.%s", "www", local.zones)`,
+		`formatlist("%s=%s", ["a", "b"], ["c", "d"])`,
+		`formatlist("%s=%s-%s", ["a", "b"], ["c", "d"], var.domain)`,
+		`formatlist("%[2]s/%[1]s", var.names, var.domain)`,
+		`formatlist("%d:%s", range(2), var.domain)`,
+		`formatlist("$%s%%", var.names)`,
+		`formatlist("%s", var.names)`,
+	}
+
+	ctx := &hcl.EvalContext{
+		Variables: map[string]cty.Value{
+			"var": cty.ObjectVal(map[string]cty.Value{
+				"names":  cty.ListVal([]cty.Value{cty.StringVal("a"), cty.StringVal("${b}")}),
+				"domain": cty.StringVal("example.com"),
+			}),
+			"local": cty.ObjectVal(map[string]cty.Value{
+				"zones": cty.ListVal([]cty.Value{cty.StringVal("z1"), cty.StringVal("z2")}),
+			}),
+		},
+		Functions: map[string]function.Function{
+			"formatlist": stdlib.FormatListFunc,
+			"range":      stdlib.RangeFunc,
+		},
+	}
+
+	for _, expr := range testCases {
+		t.Run(expr, func(t *testing.T) {
+			call := parseFormatlistCall(t, expr)
+			result, err := convertFormatlistToFor(call, []byte(expr))
+			if err != nil {
+				t.Fatalf("convertFormatlistToFor(%s) error = %v", expr, err)
+			}
+
+			// the for expression returns a tuple, which terraform converts to a list wherever a list is expected
+			expected := evaluateExpression(t, expr, ctx)
+			actual, err := convert.Convert(evaluateExpression(t, result, ctx), expected.Type())
+			if err != nil {
+				t.Fatalf("convertFormatlistToFor(%s) = %s, which is not a %s: %v", expr, result, expected.Type().FriendlyName(), err)
+			}
+			if !expected.RawEquals(actual) {
+				t.Errorf("convertFormatlistToFor(%s) = %s, evaluates to %#v; want %#v", expr, result, actual, expected)
+			}
+		})
+	}
+}
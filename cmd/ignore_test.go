@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func TestIgnoreRules(t *testing.T) {
+	testCases := []struct {
+		name            string
+		base            string
+		patterns        []string
+		path            string
+		isDir           bool
+		expectedIgnored bool
+	}{
+		{
+			name:            "no patterns",
+			base:            ".",
+			patterns:        []string{},
+			path:            "main.tf",
+			expectedIgnored: false,
+		},
+		{
+			name:            "basename matches at any depth",
+			base:            ".",
+			patterns:        []string{"*.generated.tf"},
+			path:            "stacks/a/vpc.generated.tf",
+			expectedIgnored: true,
+		},
+		{
+			name:            "anchored pattern only matches from the base",
+			base:            ".",
+			patterns:        []string{"/legacy"},
+			path:            "stacks/legacy",
+			isDir:           true,
+			expectedIgnored: false,
+		},
+		{
+			name:            "double star matches any depth",
+			base:            ".",
+			patterns:        []string{"stacks/**/old.tf"},
+			path:            "stacks/a/b/old.tf",
+			expectedIgnored: true,
+		},
+		{
+			name:            "dir only pattern skips files",
+			base:            ".",
+			patterns:        []string{"gen/"},
+			path:            "gen",
+			isDir:           false,
+			expectedIgnored: false,
+		},
+		{
+			name:            "negation re-includes",
+			base:            ".",
+			patterns:        []string{"*.tf", "!main.tf"},
+			path:            "main.tf",
+			expectedIgnored: false,
+		},
+		{
+			name:            "pattern relative to nested .gitignore",
+			base:            "stacks",
+			patterns:        []string{"/a"},
+			path:            "stacks/a",
+			isDir:           true,
+			expectedIgnored: true,
+		},
+		{
+			name:            "nested .gitignore does not apply outside its dir",
+			base:            "stacks",
+			patterns:        []string{"a"},
+			path:            "a",
+			isDir:           true,
+			expectedIgnored: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rules := parseIgnorePatterns(tc.base, tc.patterns)
+			result := rules.ignores(tc.path, tc.isDir)
+			if result != tc.expectedIgnored {
+				t.Errorf("ignores(%s) with patterns %v = %t; want %t", tc.path, tc.patterns, result, tc.expectedIgnored)
+			}
+		})
+	}
+}
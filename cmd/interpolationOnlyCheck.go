@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// interpolationOnlyTemplate is a template like "${var.name}", that evaluates to the wrapped expression as is
+type interpolationOnlyTemplate struct {
+	wrap       *hclsyntax.TemplateWrapExpr
+	attr       *hclsyntax.Attribute
+	hclAddress hclAddress
+
+	// replacement is the bare expression that replaces the template
+	replacement string
+}
+
+func init() {
+	registerRule(rule{
+		id:          "interpolation-only-template",
+		name:        "interpolation-only templates",
+		description: "Templates with only a single interpolation, that can be written as the bare expression",
+		severity:    severityWarning,
+		check:       checkInterpolationOnlyTemplates,
+		fix:         performInterpolationOnlyFix,
+	})
+}
+
+// CHECK
+
+func checkInterpolationOnlyTemplates(tfFiles []string) ([]violation, error) {
+	var violations []violation
+	for _, f := range tfFiles {
+		templates, err := checkForInterpolationOnlyInFile(f)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, t := range templates {
+			violations = append(violations, violation{
+				group:       fmt.Sprintf("file '%v'", f),
+				address:     t.hclAddress,
+				rng:         t.wrap.Range(),
+				text:        sourceText(t.wrap.Range()),
+				replacement: &t.replacement,
+			})
+		}
+	}
+
+	return violations, nil
+}
+
+func checkForInterpolationOnlyInFile(filename string) ([]interpolationOnlyTemplate, error) {
+	hclFile := parseHclFileOrSkip(filename)
+	if hclFile == nil {
+		return nil, nil
+	}
+
+	var result []interpolationOnlyTemplate
+	walkAttributes(hclFile.Body.(*hclsyntax.Body), nil, func(attr *hclsyntax.Attribute, address hclAddress) {
+		w := &wrapFinder{src: hclFile.Bytes}
+		hclsyntax.Walk(attr.Expr, w)
+		for _, t := range w.found {
+			t.attr = attr
+			t.hclAddress = address
+			result = append(result, t)
+		}
+	})
+
+	return result, nil
+}
+
+// wrapFinder walks an expression to find the interpolation-only templates, while keeping track of the parent of each
+// node, to determine whether the bare expression needs parentheses in its place
+type wrapFinder struct {
+	src     []byte
+	parents []hclsyntax.Node
+	found   []interpolationOnlyTemplate
+}
+
+func (w *wrapFinder) Enter(node hclsyntax.Node) hcl.Diagnostics {
+	if wrap, ok := node.(*hclsyntax.TemplateWrapExpr); ok {
+		var parent hclsyntax.Node
+		if len(w.parents) > 0 {
+			parent = w.parents[len(w.parents)-1]
+		}
+
+		replacement := string(wrap.Wrapped.Range().SliceBytes(w.src))
+		if needsParentheses(wrap.Wrapped, parent) {
+			replacement = "(" + replacement + ")"
+		}
+		w.found = append(w.found, interpolationOnlyTemplate{wrap: wrap, replacement: replacement})
+	}
+
+	w.parents = append(w.parents, node)
+	return nil
+}
+
+func (w *wrapFinder) Exit(node hclsyntax.Node) hcl.Diagnostics {
+	w.parents = w.parents[:len(w.parents)-1]
+	return nil
+}
+
+// needsParentheses returns whether the expression needs parentheses, when it replaces a template in the parent
+func needsParentheses(expr hclsyntax.Expression, parent hclsyntax.Node) bool {
+	switch parent.(type) {
+	case nil, *hclsyntax.TupleConsExpr, *hclsyntax.FunctionCallExpr, *hclsyntax.ParenthesesExpr, *hclsyntax.TemplateWrapExpr:
+		// the expression is on its own, or separated by commas or brackets
+		return false
+	case *hclsyntax.ObjectConsKeyExpr:
+		// an object key that is a bare name, is taken literally
+		return true
+	case *hclsyntax.ObjectConsExpr:
+		// as object value, since a key is wrapped in an ObjectConsKeyExpr
+		return false
+	}
+
+	switch expr.(type) {
+	case *hclsyntax.ScopeTraversalExpr, *hclsyntax.FunctionCallExpr, *hclsyntax.TupleConsExpr,
+		*hclsyntax.ObjectConsExpr, *hclsyntax.ParenthesesExpr, *hclsyntax.LiteralValueExpr, *hclsyntax.TemplateExpr,
+		*hclsyntax.IndexExpr, *hclsyntax.RelativeTraversalExpr, *hclsyntax.ForExpr:
+		return false
+	default:
+		return true
+	}
+}
+
+// FIX
+
+func performInterpolationOnlyFix(tfFiles []string) error {
+	for _, f := range tfFiles {
+		templates, err := checkForInterpolationOnlyInFile(f)
+		if err != nil {
+			return err
+		}
+
+		var replacements []exprReplacement
+		for _, t := range templates {
+			replacements = append(replacements, exprReplacement{
+				address:   t.hclAddress,
+				exprRange: t.attr.Expr.Range(),
+				rng:       t.wrap.Range(),
+				text:      t.replacement,
+			})
+		}
+
+		if len(replacements) == 0 {
+			continue
+		}
+		if err = replaceInAttributes(f, replacements); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+func TestUnwrapInterpolationOnlyTemplates(t *testing.T) {
+	testCases := []struct {
+		name     string
+		expr     string
+		expected string
+	}{
+		{
+			name:     "no-op: template with literal",
+			expr:     `"${var.name}-x"`,
+			expected: `"${var.name}-x"`,
+		},
+		{
+			name:     "variable",
+			expr:     `"${var.name}"`,
+			expected: `var.name`,
+		},
+		{
+			name:     "strip markers",
+			expr:     `"${~ local.x ~}"`,
+			expected: `local.x`,
+		},
+		{
+			name:     "list items and function arguments",
+			expr:     `concat(["${var.a}"], "${var.b}")`,
+			expected: `concat([var.a], var.b)`,
+		},
+		{
+			name:     "conditional on its own",
+			expr:     `"${var.enabled ? 1 : 0}"`,
+			expected: `var.enabled ? 1 : 0`,
+		},
+		{
+			name:     "operand of a binary operation",
+			expr:     `"${var.a + 1}" * 2`,
+			expected: `(var.a + 1) * 2`,
+		},
+		{
+			name:     "object key and value",
+			expr:     `{ "${var.key}" = "${var.a ? 1 : 2}" }`,
+			expected: `{ (var.key) = var.a ? 1 : 2 }`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			src := []byte(tc.expr)
+			expr, diags := hclsyntax.ParseExpression(src, "dummy.tf", hcl.Pos{Line: 1, Column: 1})
+			if diags.HasErrors() {
+				t.Fatalf("expression '%s' is not valid HCL: diagnostics: %v", tc.expr, diags)
+			}
+
+			w := &wrapFinder{src: src}
+			hclsyntax.Walk(expr, w)
+
+			var replacements []exprReplacement
+			for _, found := range w.found {
+				replacements = append(replacements, exprReplacement{rng: found.wrap.Range(), text: found.replacement})
+			}
+
+			result := spliceReplacements(src, expr.Range(), replacements)
+			if result != tc.expected {
+				t.Errorf("unwrapping %s = %s; want %s", tc.expr, result, tc.expected)
+			}
+		})
+	}
+}
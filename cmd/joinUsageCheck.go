@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const joinUsageRuleId = "join-usage"
+
+type joinInvocation struct {
+	call       *hclsyntax.FunctionCallExpr
+	attr       *hclsyntax.Attribute
+	hclAddress hclAddress
+
+	// replacement is the string template that replaces the call
+	replacement string
+}
+
+func init() {
+	registerRule(rule{
+		id:          joinUsageRuleId,
+		name:        "join() usages",
+		description: "Usages of join() on literal lists, that can be written as a string template",
+		severity:    severityWarning,
+		options:     []string{"separators"},
+		check:       checkJoinUsages,
+		fix:         performJoinUsageFix,
+	})
+}
+
+// CHECK
+
+func checkJoinUsages(tfFiles []string) ([]violation, error) {
+	var violations []violation
+	for _, f := range tfFiles {
+		invocations, err := checkForJoinUsageInFile(f)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, invoke := range invocations {
+			violations = append(violations, violation{
+				group:       fmt.Sprintf("file '%v'", f),
+				address:     invoke.hclAddress,
+				rng:         invoke.call.Range(),
+				text:        sourceText(invoke.call.Range()),
+				replacement: &invoke.replacement,
+			})
+		}
+	}
+
+	return violations, nil
+}
+
+func checkForJoinUsageInFile(filename string) ([]joinInvocation, error) {
+	separators, err := getJoinSeparators()
+	if err != nil {
+		return nil, err
+	}
+
+	hclFile := parseHclFileOrSkip(filename)
+	if hclFile == nil {
+		return nil, nil
+	}
+
+	var result []joinInvocation
+	walkAttributes(hclFile.Body.(*hclsyntax.Body), nil, func(attr *hclsyntax.Attribute, address hclAddress) {
+		var convertedRanges []hcl.Range
+		for _, call := range findFunctionCalls(attr.Expr, "join") {
+			if isNestedInAny(call.Range(), convertedRanges) {
+				continue
+			}
+
+			separator, parts, err := joinCallParts(call, hclFile.Bytes)
+			if err != nil || !contains(separators, separator) {
+				// joining an actual list is fine
+				continue
+			}
+
+			convertedRanges = append(convertedRanges, call.Range())
+			result = append(result, joinInvocation{call, attr, address, buildQuotedTemplate(parts)})
+		}
+	})
+
+	return result, nil
+}
+
+// getJoinSeparators returns the separators for which join() is converted, which is only the empty separator, unless
+// configured otherwise with the "separators" option
+func getJoinSeparators() ([]string, error) {
+	val, exists := getRuleOption(joinUsageRuleId, "separators")
+	if !exists {
+		return []string{""}, nil
+	}
+
+	if val.IsNull() || !(val.Type().IsListType() || val.Type().IsTupleType() || val.Type().IsSetType()) {
+		return nil, errors.New("option 'separators' of rule 'join-usage' must be a list of strings")
+	}
+
+	var separators []string
+	for it := val.ElementIterator(); it.Next(); {
+		_, sep := it.Element()
+		if sep.IsNull() || sep.Type() != cty.String {
+			return nil, errors.New("option 'separators' of rule 'join-usage' must be a list of strings")
+		}
+		separators = append(separators, sep.AsString())
+	}
+	return separators, nil
+}
+
+// joinCallParts returns the literal separator, and the template parts that the join() call evaluates to, when the
+// separator is a literal string, and the lists are literal lists
+func joinCallParts(call *hclsyntax.FunctionCallExpr, src []byte) (string, []templatePart, error) {
+	if call.ExpandFinal {
+		return "", nil, errors.New("arguments that are expanded with '...' are not supported")
+	}
+	if len(call.Args) < 2 {
+		return "", nil, errors.New("list is missing")
+	}
+
+	separator, ok := stringLiteralValue(call.Args[0])
+	if !ok {
+		return "", nil, errors.New("separator is not a literal string")
+	}
+
+	var parts []templatePart
+	var items []hclsyntax.Expression
+	for _, arg := range call.Args[1:] {
+		list, ok := arg.(*hclsyntax.TupleConsExpr)
+		if !ok {
+			return "", nil, errors.New("list is not a literal list")
+		}
+
+		for _, item := range list.Exprs {
+			if len(items) > 0 {
+				parts = append(parts, templatePart{literal: separator})
+			}
+			items = append(items, item)
+			parts = append(parts, argTemplateParts(item, src)...)
+		}
+	}
+
+	// a template with a single interpolation evaluates to the value itself, instead of converting it to a string
+	if len(parts) == 1 && parts[0].expr != "" {
+		decls, err := getModuleDeclarations(path.Dir(call.Range().Filename))
+		if err != nil {
+			return "", nil, err
+		}
+		if getFmtArg(items[0], decls, 0).ty != cty.String {
+			return "", nil, errors.New("the list item is not known to be a string, while the template would return it as is")
+		}
+	}
+
+	return separator, parts, nil
+}
+
+// FIX
+
+func performJoinUsageFix(tfFiles []string) error {
+	for _, f := range tfFiles {
+		invocations, err := checkForJoinUsageInFile(f)
+		if err != nil {
+			return err
+		}
+
+		var replacements []exprReplacement
+		for _, invoke := range invocations {
+			replacements = append(replacements, exprReplacement{
+				address:   invoke.hclAddress,
+				exprRange: invoke.attr.Expr.Range(),
+				rng:       invoke.call.Range(),
+				text:      invoke.replacement,
+			})
+		}
+
+		if len(replacements) == 0 {
+			continue
+		}
+		if err = replaceInAttributes(f, replacements); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestConvertJoinToTemplate(t *testing.T) {
+	useFormatTestModule(t)
+	testCases := []struct {
+		name              string
+		expr              string
+		expectedSeparator string
+		expected          string
+		expectedErr       bool
+	}{
+		{
+			name:     "literals and expressions",
+			expr:     `join("", [var.a, "b", local.c])`,
+			expected: `"${var.a}b${local.c}"`,
+		},
+		{
+			name:              "literal separator",
+			expr:              `join("-", [var.a, "b"])`,
+			expectedSeparator: "-",
+			expected:          `"${var.a}-b"`,
+		},
+		{
+			name:     "multiple lists",
+			expr:     `join("", [var.a], ["b", var.c])`,
+			expected: `"${var.a}b${var.c}"`,
+		},
+		{
+			name:     "empty list",
+			expr:     `join("", [])`,
+			expected: `""`,
+		},
+		{
+			name:     "nested calls are inlined",
+			expr:     `join("", [format("%s.", var.hoi), join(",", ["b", "c"]), lookup(var.m, "k")])`,
+			expected: `"${var.hoi}.b,c${lookup(var.m, "k")}"`,
+		},
+		{
+			name:     "single string item",
+			expr:     `join("", [var.hoi])`,
+			expected: `"${var.hoi}"`,
+		},
+		{
+			name:        "single number item",
+			expr:        `join("", [var.pct])`,
+			expectedErr: true,
+		},
+		{
+			name:        "single untyped item",
+			expr:        `join("", [var.untyped])`,
+			expectedErr: true,
+		},
+		{
+			name:     "single number item with literal",
+			expr:     `join("", [var.pct, "%"])`,
+			expected: `"${var.pct}%"`,
+		},
+		{
+			name:        "list variable",
+			expr:        `join("", var.list)`,
+			expectedErr: true,
+		},
+		{
+			name:        "separator variable",
+			expr:        `join(var.sep, ["a", "b"])`,
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			src := []byte(tc.expr)
+			expr, diags := hclsyntax.ParseExpression(src, "dummy.tf", hcl.Pos{Line: 1, Column: 1})
+			if diags.HasErrors() {
+				t.Fatalf("expression '%s' is not valid HCL: diagnostics: %v", tc.expr, diags)
+			}
+
+			separator, parts, err := joinCallParts(expr.(*hclsyntax.FunctionCallExpr), src)
+			if (err != nil) != tc.expectedErr {
+				t.Fatalf("joinCallParts(%s) error = %v; want error: %t", tc.expr, err, tc.expectedErr)
+			}
+			if err != nil {
+				return
+			}
+
+			result := buildQuotedTemplate(parts)
+			if separator != tc.expectedSeparator || result != tc.expected {
+				t.Errorf("joinCallParts(%s) = %q, %s; want %q, %s", tc.expr, separator, result, tc.expectedSeparator, tc.expected)
+			}
+		})
+	}
+}
+
+func TestGetJoinSeparators(t *testing.T) {
+	defer delete(ruleOptions, joinUsageRuleId)
+
+	separators, err := getJoinSeparators()
+	if err != nil || !reflect.DeepEqual(separators, []string{""}) {
+		t.Errorf("getJoinSeparators() = %q, %v; want only the empty separator by default", separators, err)
+	}
+
+	ruleOptions[joinUsageRuleId] = map[string]cty.Value{
+		"separators": cty.TupleVal([]cty.Value{cty.StringVal(""), cty.StringVal("-")}),
+	}
+	separators, err = getJoinSeparators()
+	if err != nil || !reflect.DeepEqual(separators, []string{"", "-"}) {
+		t.Errorf("getJoinSeparators() = %q, %v; want the configured separators", separators, err)
+	}
+
+	ruleOptions[joinUsageRuleId] = map[string]cty.Value{"separators": cty.StringVal("-")}
+	if _, err = getJoinSeparators(); err == nil {
+		t.Errorf("getJoinSeparators() succeeded for a separators option that is not a list")
+	}
+}
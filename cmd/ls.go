@@ -25,6 +25,10 @@ func runLsCmd(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	if err = applyConfig(cmd); err != nil {
+		return err
+	}
+
 	tfFiles, err := getTerraformFiles()
 	if err != nil {
 		return err
@@ -37,19 +41,19 @@ func runLsCmd(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	referencedModules, err := getReferencedModules(tfFiles)
+	referencedModules, err := getModuleCallTree(tfFiles)
 	if err != nil {
 		return err
 	}
 
 	if len(referencedModules) == 0 {
 		fmt.Println("No modules detected in any of the TF files")
-		return nil
+		return skippedFilesError(cmd)
 	}
 
 	fmt.Println("Detected modules:")
 	for _, mod := range referencedModules {
-		fmt.Printf("\t%v", mod.name())
+		fmt.Printf("\t%v", mod.displayName())
 		if verbose {
 			fmt.Printf(" (%v)", mod.location())
 		}
@@ -60,7 +64,7 @@ func runLsCmd(cmd *cobra.Command, args []string) error {
 		fmt.Printf("\n\nVariables for modules:\n")
 
 		for _, mod := range referencedModules {
-			fmt.Printf("\n%v:\n", mod.name())
+			fmt.Printf("\n%v:\n", mod.displayName())
 			vars, err := getModuleVariables(mod)
 			if err != nil {
 				return err
@@ -75,5 +79,5 @@ func runLsCmd(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	return nil
+	return skippedFilesError(cmd)
 }
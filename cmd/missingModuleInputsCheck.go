@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// missingModuleInput is a required variable of a module, that the module call doesn't assign
+type missingModuleInput struct {
+	mod      module
+	variable variableDefinition
+}
+
+func init() {
+	registerRule(rule{
+		id:          "missing-module-input",
+		name:        "missing module inputs",
+		description: "Required variables of a module, that the module call doesn't assign",
+		severity:    severityError,
+		check:       checkMissingModuleInputs,
+	})
+}
+
+func checkMissingModuleInputs(tfFiles []string) ([]violation, error) {
+	missingInputs, err := checkForMissingModuleInputs(tfFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []violation
+	for _, input := range missingInputs {
+		var details []string
+		if typeAttr := getAttribute(input.variable.bl.Body, "type"); typeAttr != nil {
+			details = append(details, "type: "+sourceText(typeAttr.Expr.Range()))
+		}
+		if description := getStringAttribute(input.variable.bl.Body, "description"); description != "" {
+			details = append(details, "description: "+description)
+		}
+
+		violations = append(violations, violation{
+			group:   input.mod.description(),
+			address: input.mod.hclAddress(input.variable.name()),
+			rng:     input.mod.bl.DefRange(),
+			text:    input.variable.name(),
+			note:    strings.Join(details, "; "),
+		})
+	}
+
+	return violations, nil
+}
+
+func checkForMissingModuleInputs(tfFiles []string) ([]missingModuleInput, error) {
+	referencedModules, err := getModuleCallTree(tfFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []missingModuleInput
+	for _, mod := range referencedModules {
+		if mod.isInstalled() {
+			// the module calls of installed modules cannot be fixed here
+			continue
+		}
+
+		moduleDir, err := resolveModuleDir(mod)
+		if err != nil {
+			return nil, err
+		}
+		if moduleDir == "" {
+			// the variables of the module are unknown
+			continue
+		}
+
+		skipped, err := hasSkippedFiles(moduleDir)
+		if err != nil {
+			return nil, err
+		}
+		if skipped {
+			// the required variables might be declared in the skipped files, which already fail the check
+			continue
+		}
+
+		moduleVariables, err := getModuleVariables(mod)
+		if err != nil {
+			return nil, err
+		}
+		sort.SliceStable(moduleVariables, func(i, j int) bool {
+			return moduleVariables[i].name() < moduleVariables[j].name()
+		})
+
+		// meta-arguments like count and for_each are no inputs, so they don't count as assignments
+		assignments := filterForTerraformAssignments(getVariableAssignments(mod))
+		for _, v := range moduleVariables {
+			if _, assigned := assignments[v.name()]; !assigned && v.isRequired() {
+				result = append(result, missingModuleInput{mod, v})
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// isRequired returns whether the variable must be assigned, which is when it has no default, or a null default while
+// it is not nullable
+func (v variableDefinition) isRequired() bool {
+	defaultValue := v.defaultValue()
+	if defaultValue == nil {
+		return true
+	}
+
+	val, diags := defaultValue.attr.Expr.Value(nil)
+	if diags.HasErrors() || !val.IsNull() {
+		return false
+	}
+
+	nullable := getAttribute(v.bl.Body, "nullable")
+	if nullable == nil {
+		return false
+	}
+	nullableVal, diags := nullable.Expr.Value(nil)
+	return !diags.HasErrors() && nullableVal.RawEquals(cty.False)
+}
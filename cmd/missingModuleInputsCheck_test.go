@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+func TestVariableIsRequired(t *testing.T) {
+	testCases := []struct {
+		name     string
+		hcl      string
+		expected bool
+	}{
+		{name: "no default", hcl: `variable "x" {}`, expected: true},
+		{name: "default", hcl: `variable "x" { default = "a" }`, expected: false},
+		{name: "null default", hcl: `variable "x" { default = null }`, expected: false},
+		{
+			name:     "null default, not nullable",
+			hcl:      "variable \"x\" {\n  default  = null\n  nullable = false\n}",
+			expected: true,
+		},
+		{
+			name:     "default, not nullable",
+			hcl:      "variable \"x\" {\n  default  = []\n  nullable = false\n}",
+			expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hclFile, diags := hclsyntax.ParseConfig([]byte(tc.hcl), "dummy.tf", hcl.Pos{Line: 1, Column: 1})
+			if diags.HasErrors() {
+				t.Fatalf("'%s' is not valid HCL: diagnostics: %v", tc.hcl, diags)
+			}
+
+			v := variableDefinition{hclFile.Body.(*hclsyntax.Body).Blocks[0]}
+			if result := v.isRequired(); result != tc.expected {
+				t.Errorf("isRequired(%s) = %t; want %t", tc.hcl, result, tc.expected)
+			}
+		})
+	}
+}
+
+func TestMissingModuleInputsWithSkippedModuleFile(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"main.tf":        "module \"m\" {\n  source = \"./modules/m\"\n}\n\nmodule \"n\" {\n  source = \"./modules/n\"\n}\n",
+		"modules/m/a.tf": "variable \"name\" {}\n",
+		"modules/m/b.tf": "variable \"size\" {\n  default = 1\n",
+		"modules/n/a.tf": "variable \"name\" {}\n",
+	})
+	chdirForTest(t, dir)
+
+	missingInputs, err := checkForMissingModuleInputs([]string{"main.tf"})
+	if err != nil {
+		t.Fatalf("checkForMissingModuleInputs() error = %v", err)
+	}
+
+	// the module with the skipped file cannot be verified, so only the other module is reported
+	if len(missingInputs) != 1 || missingInputs[0].mod.name() != "n" || missingInputs[0].variable.name() != "name" {
+		t.Errorf("checkForMissingModuleInputs() = %+v; want only 'name' of module 'n'", missingInputs)
+	}
+}
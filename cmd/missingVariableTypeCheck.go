@@ -0,0 +1,350 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// untypedVariable is a variable without a type constraint, with the constraint that is inferred for it, if any
+type untypedVariable struct {
+	variableDefinition
+	dir        string
+	constraint string
+	inferredBy string
+}
+
+func init() {
+	registerRule(rule{
+		id:          "missing-variable-type",
+		name:        "variables without type",
+		description: "Variables without a type constraint",
+		severity:    severityInfo,
+		check:       checkMissingVariableTypes,
+		fix:         performMissingVariableTypeFix,
+	})
+}
+
+// CHECK
+
+func checkMissingVariableTypes(tfFiles []string) ([]violation, error) {
+	untypedVars, err := checkForMissingVariableTypes(tfFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []violation
+	for _, v := range untypedVars {
+		violation := violation{
+			group:   moduleDirDescription(v.dir),
+			address: hclAddress{[]hclBlockId{{typeName: "variable", labels: v.bl.Labels}}, ""},
+			rng:     v.bl.Range(),
+			text:    sourceText(v.bl.DefRange()),
+		}
+
+		if v.constraint == "" {
+			violation.note = "cannot be fixed: the type cannot be inferred from the default value or the module calls"
+		} else {
+			replacement, err := addTypeToBlockSource(v.bl, v.constraint)
+			if err != nil {
+				return nil, err
+			}
+			violation.replacement = &replacement
+			violation.note = fmt.Sprintf("type %v is inferred from %v", v.constraint, v.inferredBy)
+		}
+		violations = append(violations, violation)
+	}
+
+	return violations, nil
+}
+
+func checkForMissingVariableTypes(tfFiles []string) ([]untypedVariable, error) {
+	moduleCalls, err := getModuleCallTree(tfFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []untypedVariable
+	for _, dir := range getModuleDirs(tfFiles) {
+		callers, err := getLocalModuleCalls(moduleCalls, dir)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, f := range tfFiles {
+			if path.Dir(f) != dir {
+				continue
+			}
+
+			vars, err := readVariables(f)
+			if err != nil {
+				return nil, err
+			}
+			for _, v := range vars {
+				if getAttribute(v.bl.Body, "type") != nil {
+					continue
+				}
+
+				untyped := untypedVariable{variableDefinition: v, dir: dir}
+				untyped.constraint, untyped.inferredBy, err = inferVariableType(v, callers)
+				if err != nil {
+					return nil, err
+				}
+				result = append(result, untyped)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// inferVariableType returns the type constraint for the variable, and what it is inferred from, where the default
+// value is preferred, unless its type is not fully known, like for an empty list
+func inferVariableType(v variableDefinition, callers []module) (string, string, error) {
+	var defaultConstraint string
+	defaultExact := false
+	if defaultValue := v.defaultValue(); defaultValue != nil {
+		if val, ok := staticValue(defaultValue.attr.Expr); ok {
+			defaultConstraint, defaultExact = typeConstraint(val.Type())
+		}
+	}
+	if defaultExact {
+		return defaultConstraint, "the default value", nil
+	}
+
+	callerConstraint, err := inferTypeFromCallers(v.name(), callers)
+	if err != nil {
+		return "", "", err
+	}
+	if callerConstraint != "" {
+		return callerConstraint, "the module calls", nil
+	}
+
+	if defaultConstraint != "" {
+		return defaultConstraint, "the default value", nil
+	}
+	return "", "", nil
+}
+
+// inferTypeFromCallers returns the type constraint that all module calls that assign the variable agree on, if any
+func inferTypeFromCallers(name string, callers []module) (string, error) {
+	var constraint string
+	for _, mod := range callers {
+		assign, exists := getVariableAssignments(mod)[name]
+		if !exists {
+			continue
+		}
+
+		callerConstraint, err := inferTypeFromAssignment(assign.attr.Expr, path.Dir(mod.filename()))
+		if err != nil || callerConstraint == "" {
+			return "", err
+		}
+		if constraint != "" && callerConstraint != constraint {
+			return "", nil
+		}
+		constraint = callerConstraint
+	}
+	return constraint, nil
+}
+
+// inferTypeFromAssignment returns the type constraint of the assigned value, which is either a static value, or a
+// variable of the calling module with a type constraint
+func inferTypeFromAssignment(expr hclsyntax.Expression, callerDir string) (string, error) {
+	if val, ok := staticValue(expr); ok {
+		if constraint, exact := typeConstraint(val.Type()); exact {
+			return constraint, nil
+		}
+		return "", nil
+	}
+
+	traversal, ok := expr.(*hclsyntax.ScopeTraversalExpr)
+	if !ok || len(traversal.Traversal) != 2 {
+		return "", nil
+	}
+	name := getVariableName(traversal.Traversal)
+	if name == "" {
+		return "", nil
+	}
+
+	filenames, err := getDirTerraformFiles(callerDir)
+	if err != nil {
+		return "", err
+	}
+	for _, f := range filenames {
+		vars, err := readVariables(f)
+		if err != nil {
+			return "", err
+		}
+		for _, v := range vars {
+			if typeAttr := getAttribute(v.bl.Body, "type"); v.name() == name && typeAttr != nil {
+				return sourceText(typeAttr.Expr.Range()), nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// staticValue returns the value of the expression, when it can be evaluated without any variables or functions
+func staticValue(expr hclsyntax.Expression) (cty.Value, bool) {
+	val, diags := expr.Value(nil)
+	if diags.HasErrors() || !val.IsWhollyKnown() || val.IsNull() {
+		return cty.NilVal, false
+	}
+	return val, true
+}
+
+// typeConstraint returns the type constraint for values of the type, and whether it is exact, so without "any" for
+// the element type of empty collections. Tuples and objects with elements of a single type are written as lists and
+// maps, as that is what they are mostly meant to be.
+func typeConstraint(ty cty.Type) (string, bool) {
+	switch {
+	case ty == cty.String:
+		return "string", true
+	case ty == cty.Number:
+		return "number", true
+	case ty == cty.Bool:
+		return "bool", true
+	case ty.IsListType():
+		return collectionConstraint("list", ty.ElementType())
+	case ty.IsSetType():
+		return collectionConstraint("set", ty.ElementType())
+	case ty.IsMapType():
+		return collectionConstraint("map", ty.ElementType())
+	case ty.IsTupleType():
+		elemTypes := ty.TupleElementTypes()
+		if elemType, ok := singleType(elemTypes); ok {
+			return collectionConstraint("list", elemType)
+		}
+
+		var elems []string
+		exact := true
+		for _, elemType := range elemTypes {
+			elem, elemExact := typeConstraint(elemType)
+			elems = append(elems, elem)
+			exact = exact && elemExact
+		}
+		return "tuple([" + strings.Join(elems, ", ") + "])", exact
+	case ty.IsObjectType():
+		var names []string
+		var attrTypes []cty.Type
+		for name := range ty.AttributeTypes() {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			attrTypes = append(attrTypes, ty.AttributeType(name))
+		}
+
+		if attrType, ok := singleType(attrTypes); ok {
+			return collectionConstraint("map", attrType)
+		}
+
+		var attrs []string
+		exact := true
+		for i, name := range names {
+			if !hclsyntax.ValidIdentifier(name) {
+				// object type constraints only support plain attribute names
+				return "any", false
+			}
+			attr, attrExact := typeConstraint(attrTypes[i])
+			attrs = append(attrs, name+" = "+attr)
+			exact = exact && attrExact
+		}
+		return "object({ " + strings.Join(attrs, ", ") + " })", exact
+	default:
+		return "any", false
+	}
+}
+
+func collectionConstraint(kind string, elemType cty.Type) (string, bool) {
+	elem, exact := typeConstraint(elemType)
+	return kind + "(" + elem + ")", exact
+}
+
+// singleType returns the type that all types are equal to, where there is no such type for an empty list
+func singleType(types []cty.Type) (cty.Type, bool) {
+	if len(types) == 0 {
+		return cty.DynamicPseudoType, true
+	}
+	for _, ty := range types[1:] {
+		if !ty.Equals(types[0]) {
+			return cty.NilType, false
+		}
+	}
+	return types[0], true
+}
+
+// addTypeToBlockSource returns the source of the variable block with the type constraint added to it
+func addTypeToBlockSource(bl *hclsyntax.Block, constraint string) (string, error) {
+	hclFile, diags := hclwrite.ParseConfig([]byte(sourceText(bl.Range())+"\n"), bl.Range().Filename, hcl.InitialPos)
+	if diags.HasErrors() || len(hclFile.Body().Blocks()) != 1 {
+		return "", fmt.Errorf("failed to parse variable '%v': %s", blockName(bl), diags.Error())
+	}
+
+	tokens, err := parseExpressionTokens(constraint)
+	if err != nil {
+		return "", err
+	}
+	setTypeAttribute(hclFile.Body().Blocks()[0], bl, tokens)
+
+	return strings.TrimSuffix(string(hclwrite.Format(hclFile.Bytes())), "\n"), nil
+}
+
+// setTypeAttribute adds the type attribute to the block, where a single-line block like `variable "x" {}` is turned
+// into a multi-line block first, as it cannot hold more than one attribute. The content of the single-line block is
+// kept as is, including its comments.
+func setTypeAttribute(bl *hclwrite.Block, original *hclsyntax.Block, tokens hclwrite.Tokens) {
+	body := bl.Body()
+	if original.OpenBraceRange.Start.Line == original.CloseBraceRange.Start.Line {
+		content := body.BuildTokens(nil)
+		body.Clear()
+		body.AppendNewline()
+		if len(content) > 0 {
+			content[0].SpacesBefore = 0
+			body.AppendUnstructuredTokens(content)
+			body.AppendNewline()
+		}
+	}
+
+	body.SetAttributeRaw("type", tokens)
+}
+
+// FIX
+
+func performMissingVariableTypeFix(tfFiles []string) error {
+	untypedVars, err := checkForMissingVariableTypes(tfFiles)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range untypedVars {
+		if v.constraint == "" {
+			rng := v.bl.DefRange()
+			reportWarning(&rng, fmt.Sprintf("variable '%v' is not typed", v.name()), "the type cannot be inferred")
+			continue
+		}
+
+		tokens, err := parseExpressionTokens(v.constraint)
+		if err != nil {
+			return err
+		}
+
+		err = patchFile(v.bl.Range().Filename, func(hclFile *hclwrite.File) (*hclwrite.File, error) {
+			if bl := hclFile.Body().FirstMatchingBlock("variable", v.bl.Labels); bl != nil {
+				setTypeAttribute(bl, v.bl, tokens)
+			}
+			return hclFile, nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
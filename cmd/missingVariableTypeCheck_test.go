@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+func TestTypeConstraint(t *testing.T) {
+	testCases := []struct {
+		name          string
+		value         string
+		expected      string
+		expectedExact bool
+	}{
+		{name: "string", value: `"hoi"`, expected: "string", expectedExact: true},
+		{name: "number", value: `42`, expected: "number", expectedExact: true},
+		{name: "bool", value: `true`, expected: "bool", expectedExact: true},
+		{name: "list of strings", value: `["a", "b"]`, expected: "list(string)", expectedExact: true},
+		{name: "empty list", value: `[]`, expected: "list(any)", expectedExact: false},
+		{name: "mixed list", value: `["a", 1]`, expected: "tuple([string, number])", expectedExact: true},
+		{name: "map of strings", value: `{ Name = "a", Env = "b" }`, expected: "map(string)", expectedExact: true},
+		{name: "empty map", value: `{}`, expected: "map(any)", expectedExact: false},
+		{
+			name:          "object",
+			value:         `{ enabled = true, size = 10 }`,
+			expected:      "object({ enabled = bool, size = number })",
+			expectedExact: true,
+		},
+		{
+			name:          "map of objects",
+			value:         `{ web = { port = 80, name = "web" }, api = { port = 81, name = "api" } }`,
+			expected:      "map(object({ name = string, port = number }))",
+			expectedExact: true,
+		},
+		{
+			name:          "list of objects",
+			value:         `[{ port = 80, tags = [] }]`,
+			expected:      "list(object({ port = number, tags = list(any) }))",
+			expectedExact: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			expr, diags := hclsyntax.ParseExpression([]byte(tc.value), "dummy.tf", hcl.Pos{Line: 1, Column: 1})
+			if diags.HasErrors() {
+				t.Fatalf("expression '%s' is not valid HCL: diagnostics: %v", tc.value, diags)
+			}
+			val, ok := staticValue(expr)
+			if !ok {
+				t.Fatalf("expression '%s' has no static value", tc.value)
+			}
+
+			result, exact := typeConstraint(val.Type())
+			if result != tc.expected || exact != tc.expectedExact {
+				t.Errorf("typeConstraint(%s) = %s, %t; want %s, %t", tc.value, result, exact, tc.expected, tc.expectedExact)
+			}
+		})
+	}
+}
+
+func TestMissingVariableTypeFix(t *testing.T) {
+	testCases := []struct {
+		name     string
+		src      string
+		expected string
+	}{
+		{
+			name:     "single-line block",
+			src:      "variable \"x\" { default = 1 }\n",
+			expected: "variable \"x\" {\n  default = 1\n  type    = number\n}\n",
+		},
+		{
+			name:     "single-line block with inline comment",
+			src:      "variable \"x\" { default = 1 /* seconds */ }\n",
+			expected: "variable \"x\" {\n  default = 1 /* seconds */\n  type    = number\n}\n",
+		},
+		{
+			name:     "single-line block with comment after it",
+			src:      "variable \"x\" { default = \"a\" } # name\n",
+			expected: "variable \"x\" {\n  default = \"a\"\n  type    = string\n} # name\n",
+		},
+		{
+			name:     "multi-line block",
+			src:      "variable \"x\" {\n  # a flag\n  default     = true\n  description = \"x\"\n}\n",
+			expected: "variable \"x\" {\n  # a flag\n  default     = true\n  description = \"x\"\n  type        = bool\n}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTestFiles(t, dir, map[string]string{"main.tf": tc.src})
+			chdirForTest(t, dir)
+
+			dryRun = true
+			defer func() { dryRun = false }()
+
+			if err := performMissingVariableTypeFix([]string{"main.tf"}); err != nil {
+				t.Fatalf("performMissingVariableTypeFix() error = %v", err)
+			}
+			if result := string(pendingFiles["main.tf"]); result != tc.expected {
+				t.Errorf("performMissingVariableTypeFix() resulted in:\n%s\nwant:\n%s", result, tc.expected)
+			}
+		})
+	}
+}
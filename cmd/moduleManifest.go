@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+)
+
+const moduleManifestPath = ".terraform/modules/modules.json"
+
+// moduleManifest is the manifest that `terraform init` writes for the modules it installed
+type moduleManifest struct {
+	Modules []moduleManifestEntry `json:"Modules"`
+}
+
+type moduleManifestEntry struct {
+	Key     string `json:"Key"`
+	Source  string `json:"Source"`
+	Version string `json:"Version"`
+	Dir     string `json:"Dir"`
+}
+
+// resolvedModuleDirs caches the resolved dir per module call, to only report diagnostics once
+var resolvedModuleDirs = make(map[module]string)
+
+// moduleManifests caches the manifest per root module dir, where nil means there is no manifest
+var moduleManifests = make(map[string]*moduleManifest)
+
+func readModuleManifest(rootDir string) (*moduleManifest, error) {
+	if manifest, exists := moduleManifests[rootDir]; exists {
+		return manifest, nil
+	}
+
+	input, err := os.ReadFile(path.Join(rootDir, moduleManifestPath))
+	if errors.Is(err, fs.ErrNotExist) {
+		moduleManifests[rootDir] = nil
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest moduleManifest
+	if err = json.Unmarshal(input, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse module manifest '%v': %s", path.Join(rootDir, moduleManifestPath), err)
+	}
+
+	moduleManifests[rootDir] = &manifest
+	return &manifest, nil
+}
+
+func (manifest *moduleManifest) lookup(key string) *moduleManifestEntry {
+	for i, entry := range manifest.Modules {
+		if entry.Key == key {
+			return &manifest.Modules[i]
+		}
+	}
+	return nil
+}
+
+// resolveModuleDir returns the directory that contains the source of the module, or an empty string when it cannot be
+// found, in which case a diagnostic is reported
+func resolveModuleDir(mod module) (string, error) {
+	if dir, exists := resolvedModuleDirs[mod]; exists {
+		return dir, nil
+	}
+
+	dir, err := resolveModuleDirUncached(mod)
+	if err != nil {
+		return "", err
+	}
+
+	resolvedModuleDirs[mod] = dir
+	return dir, nil
+}
+
+func resolveModuleDirUncached(mod module) (string, error) {
+	rootDir := mod.rootDir()
+	rng := mod.bl.DefRange()
+
+	if source := mod.source(); isLocalModuleSource(source) {
+		// local modules are not installed, and can be read without requiring `terraform init`
+		dir := path.Join(path.Dir(mod.filename()), source)
+		if !isDir(dir) {
+			reportWarning(&rng, fmt.Sprintf("cannot resolve module '%v'", mod.name()),
+				fmt.Sprintf("local module dir '%v' does not exist", dir))
+			return "", nil
+		}
+		return dir, nil
+	}
+
+	manifest, err := readModuleManifest(rootDir)
+	if err != nil {
+		return "", err
+	}
+
+	if manifest == nil {
+		// fall back to the layout that `terraform init` uses for modules that are called from the root module
+		dir := path.Join(rootDir, ".terraform/modules", mod.name())
+		if mod.parentKey != "" || !isDir(dir) {
+			reportWarning(&rng, fmt.Sprintf("cannot resolve module '%v'", mod.name()),
+				fmt.Sprintf("module manifest '%v' not found, run `terraform init` first", path.Join(rootDir, moduleManifestPath)))
+			return "", nil
+		}
+		return dir, nil
+	}
+
+	entry := manifest.lookup(mod.key())
+	if entry == nil {
+		reportWarning(&rng, fmt.Sprintf("cannot resolve module '%v'", mod.name()),
+			"module is missing from the module manifest, which is stale; run `terraform init` again")
+		return "", nil
+	}
+
+	if source := mod.source(); source != "" && !isSameModuleSource(source, entry.Source) {
+		reportWarning(&rng, fmt.Sprintf("module '%v' might be outdated", mod.name()),
+			fmt.Sprintf("module manifest has source '%v' instead of '%v'; run `terraform init` again", entry.Source, source))
+	}
+
+	dir := path.Join(rootDir, entry.Dir)
+	if !isDir(dir) {
+		reportWarning(&rng, fmt.Sprintf("cannot resolve module '%v'", mod.name()),
+			fmt.Sprintf("module dir '%v' from the module manifest does not exist; run `terraform init` again", dir))
+		return "", nil
+	}
+
+	return dir, nil
+}
+
+// isLocalModuleSource returns whether the source is a local path, see
+// https://developer.hashicorp.com/terraform/language/modules/sources#local-paths
+func isLocalModuleSource(source string) bool {
+	return strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../")
+}
+
+// isSameModuleSource compares the sources, where the manifest has the registry hostname prepended for registry modules
+func isSameModuleSource(source, manifestSource string) bool {
+	return source == manifestSource || strings.HasSuffix(manifestSource, "/"+source)
+}
+
+func isDir(dir string) bool {
+	info, err := os.Stat(dir)
+	return err == nil && info.IsDir()
+}
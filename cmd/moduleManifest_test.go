@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsSameModuleSource(t *testing.T) {
+	testCases := []struct {
+		source         string
+		manifestSource string
+		expected       bool
+	}{
+		{source: "terraform-aws-modules/vpc/aws", manifestSource: "registry.terraform.io/terraform-aws-modules/vpc/aws", expected: true},
+		{source: "app.terraform.io/org/vpc/aws", manifestSource: "app.terraform.io/org/vpc/aws", expected: true},
+		{source: "terraform-aws-modules/vpc/aws", manifestSource: "registry.terraform.io/terraform-aws-modules/eks/aws", expected: false},
+		{source: "git::https://example.com/net.git//modules/vpc?ref=v1", manifestSource: "git::https://example.com/net.git//modules/vpc?ref=v1", expected: true},
+		{source: "git::https://example.com/net.git//modules/vpc?ref=v2", manifestSource: "git::https://example.com/net.git//modules/vpc?ref=v1", expected: false},
+	}
+
+	for _, tc := range testCases {
+		if result := isSameModuleSource(tc.source, tc.manifestSource); result != tc.expected {
+			t.Errorf("isSameModuleSource(%s, %s) = %t; want %t", tc.source, tc.manifestSource, result, tc.expected)
+		}
+	}
+}
+
+func TestResolveModuleDir(t *testing.T) {
+	manifest := `{
+  "Modules": [
+    {"Key": "", "Source": "", "Dir": "."},
+    {"Key": "vpc", "Source": "registry.terraform.io/terraform-aws-modules/vpc/aws", "Version": "5.0.0", "Dir": ".terraform/modules/vpc"},
+    {"Key": "subnets", "Source": "git::https://example.com/net.git//modules/subnets?ref=v1", "Dir": ".terraform/modules/subnets/modules/subnets"},
+    {"Key": "stale", "Source": "registry.terraform.io/terraform-aws-modules/eks/aws", "Version": "19.0.0", "Dir": ".terraform/modules/stale"},
+    {"Key": "removed", "Source": "registry.terraform.io/terraform-aws-modules/s3-bucket/aws", "Dir": ".terraform/modules/removed"}
+  ]
+}`
+	testCases := []struct {
+		name        string
+		module      string
+		manifest    bool
+		expectedDir string
+		diagnostic  string
+	}{
+		{
+			name:        "registry source",
+			module:      `module "vpc" { source = "terraform-aws-modules/vpc/aws" }`,
+			manifest:    true,
+			expectedDir: ".terraform/modules/vpc",
+		},
+		{
+			name:        "git source with subdir",
+			module:      `module "subnets" { source = "git::https://example.com/net.git//modules/subnets?ref=v1" }`,
+			manifest:    true,
+			expectedDir: ".terraform/modules/subnets/modules/subnets",
+		},
+		{
+			name:        "stale entry with other source",
+			module:      `module "stale" { source = "terraform-aws-modules/vpc/aws" }`,
+			manifest:    true,
+			expectedDir: ".terraform/modules/stale",
+			diagnostic:  "module 'stale' might be outdated",
+		},
+		{
+			name:       "module missing from manifest",
+			module:     `module "new" { source = "terraform-aws-modules/vpc/aws" }`,
+			manifest:   true,
+			diagnostic: "module is missing from the module manifest",
+		},
+		{
+			name:       "missing manifest",
+			module:     `module "new" { source = "terraform-aws-modules/vpc/aws" }`,
+			diagnostic: "module manifest '.terraform/modules/modules.json' not found",
+		},
+		{
+			name:        "missing manifest, with the module installed by its name",
+			module:      `module "vpc" { source = "terraform-aws-modules/vpc/aws" }`,
+			expectedDir: ".terraform/modules/vpc",
+		},
+		{
+			name:        "local source",
+			module:      `module "local" { source = "./modules/local" }`,
+			expectedDir: "modules/local",
+		},
+		{
+			name:       "local source that does not exist",
+			module:     `module "local" { source = "./modules/missing" }`,
+			diagnostic: "local module dir 'modules/missing' does not exist",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			files := map[string]string{
+				"main.tf":                        tc.module + "\n",
+				".terraform/modules/vpc/main.tf": "",
+				".terraform/modules/subnets/modules/subnets/main.tf": "",
+				".terraform/modules/stale/main.tf":                   "",
+				"modules/local/main.tf":                              "",
+			}
+			if tc.manifest {
+				files[moduleManifestPath] = manifest
+			}
+
+			dir := t.TempDir()
+			writeTestFiles(t, dir, files)
+			chdirForTest(t, dir)
+			diagnostics := captureDiagnostics(t)
+
+			modules, err := readModules("main.tf")
+			if err != nil || len(modules) != 1 {
+				t.Fatalf("readModules() = %v, %v; want a single module", modules, err)
+			}
+
+			result, err := resolveModuleDir(modules[0])
+			if err != nil {
+				t.Fatalf("resolveModuleDir() error = %v", err)
+			}
+			if result != tc.expectedDir {
+				t.Errorf("resolveModuleDir() = '%s'; want '%s'", result, tc.expectedDir)
+			}
+
+			if tc.diagnostic == "" && diagnostics.Len() > 0 {
+				t.Errorf("resolveModuleDir() reported '%s'; want no diagnostics", diagnostics)
+			}
+			if !strings.Contains(diagnostics.String(), tc.diagnostic) {
+				t.Errorf("resolveModuleDir() reported '%s'; want '%s'", diagnostics, tc.diagnostic)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	outputText  = "text"
+	outputJson  = "json"
+	outputSarif = "sarif"
+)
+
+var outputFormats = []string{outputText, outputJson, outputSarif}
+
+// reportOutput is where the report is written, which tests replace to check it
+var reportOutput io.Writer = os.Stdout
+
+// ruleResult holds the violations that a single rule found
+type ruleResult struct {
+	rule       rule
+	violations []violation
+}
+
+type jsonReport struct {
+	Violations []jsonViolation `json:"violations"`
+}
+
+type jsonViolation struct {
+	RuleId      string  `json:"rule_id"`
+	Severity    string  `json:"severity"`
+	File        string  `json:"file"`
+	StartLine   int     `json:"start_line"`
+	StartColumn int     `json:"start_column"`
+	EndLine     int     `json:"end_line"`
+	EndColumn   int     `json:"end_column"`
+	Address     string  `json:"address"`
+	Text        string  `json:"text"`
+	Replacement *string `json:"replacement,omitempty"`
+	Note        string  `json:"note,omitempty"`
+}
+
+func validateOutputFormat(format string) error {
+	if !contains(outputFormats, format) {
+		return fmt.Errorf("unknown output format '%v' (expected one of: %v)", format, strings.Join(outputFormats, ", "))
+	}
+	return nil
+}
+
+func writeReport(format string, results []ruleResult) error {
+	switch format {
+	case outputJson:
+		return writeJsonReport(results)
+	case outputSarif:
+		return writeSarifReport(results)
+	default:
+		for _, res := range results {
+			printViolations(res.rule, res.violations)
+		}
+		return nil
+	}
+}
+
+func writeJsonReport(results []ruleResult) error {
+	report := jsonReport{Violations: []jsonViolation{}}
+	for _, res := range results {
+		for _, v := range res.violations {
+			report.Violations = append(report.Violations, jsonViolation{
+				RuleId:      res.rule.id,
+				Severity:    res.rule.severity.String(),
+				File:        v.rng.Filename,
+				StartLine:   v.rng.Start.Line,
+				StartColumn: v.rng.Start.Column,
+				EndLine:     v.rng.End.Line,
+				EndColumn:   v.rng.End.Column,
+				Address:     v.address.string(),
+				Text:        v.text,
+				Replacement: v.replacement,
+				Note:        v.note,
+			})
+		}
+	}
+
+	encoder := json.NewEncoder(reportOutput)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// sortViolations orders the violations by their location, to get a stable report
+func sortViolations(violations []violation) {
+	sort.SliceStable(violations, func(i, j int) bool {
+		a, b := violations[i].rng, violations[j].rng
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		return a.Start.Byte < b.Start.Byte
+	})
+}
+
+func printViolations(r rule, violations []violation) {
+	if len(violations) == 0 {
+		fmt.Fprintf(reportOutput, "No %v were found\n", r.name)
+		return
+	}
+
+	fmt.Fprintf(reportOutput, "== RESULTS FOR THE %v (%v) ==\n", strings.ToUpper(r.name), r.severity)
+
+	var groups []string
+	grouped := make(map[string][]violation)
+	for _, v := range violations {
+		if _, exists := grouped[v.group]; !exists {
+			groups = append(groups, v.group)
+		}
+		grouped[v.group] = append(grouped[v.group], v)
+	}
+
+	for _, group := range groups {
+		fmt.Fprintf(reportOutput, "\n\tThe following %v were found for %v:\n", r.name, group)
+
+		for _, v := range grouped[group] {
+			fmt.Fprintf(reportOutput, "\t\t%v", v.text)
+			if verbose {
+				fmt.Fprintf(reportOutput, " (%v)", location(v.rng))
+			}
+			if v.note != "" {
+				fmt.Fprintf(reportOutput, " - %v", v.note)
+			}
+			fmt.Fprintln(reportOutput)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// testReportResults are the results of two rules, with a violation that is fixed by a replacement, one that is fixed
+// by a removal, and one without a fix
+func testReportResults() []ruleResult {
+	replacement := `"${var.a}-x"`
+	removal := ""
+	return []ruleResult{
+		{
+			rule: rule{id: "format-usage", name: "format() usages", description: "Usages of format() that can be a template", severity: severityWarning},
+			violations: []violation{
+				{
+					address:     hclAddress{[]hclBlockId{{typeName: "locals"}}, "a"},
+					rng:         hcl.Range{Filename: "main.tf", Start: hcl.Pos{Line: 2, Column: 7, Byte: 15}, End: hcl.Pos{Line: 2, Column: 29, Byte: 37}},
+					text:        `format("%s-x", var.a)`,
+					replacement: &replacement,
+				},
+			},
+		},
+		{
+			rule: rule{id: "unused-variable", name: "unused variables", description: "Variables that are never referenced in their module", severity: severityError},
+			violations: []violation{
+				{
+					address:     hclAddress{[]hclBlockId{{typeName: "variable", labels: []string{"b"}}}, ""},
+					rng:         hcl.Range{Filename: "modules/m/variables.tf", Start: hcl.Pos{Line: 1, Column: 1, Byte: 0}, End: hcl.Pos{Line: 3, Column: 2, Byte: 30}},
+					text:        `variable "b"`,
+					replacement: &removal,
+				},
+				{
+					address: hclAddress{[]hclBlockId{{typeName: "variable", labels: []string{"c"}}}, ""},
+					rng:     hcl.Range{Filename: "modules/m/variables.tf", Start: hcl.Pos{Line: 5, Column: 1, Byte: 32}, End: hcl.Pos{Line: 5, Column: 16, Byte: 47}},
+					text:    `variable "c"`,
+					note:    "cannot be fixed, since it is assigned by an installed module",
+				},
+			},
+		},
+	}
+}
+
+// captureReport collects the report that is written during the test
+func captureReport(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	reportOutput = &buf
+	t.Cleanup(func() {
+		reportOutput = os.Stdout
+	})
+	return &buf
+}
+
+func TestWriteJsonReport(t *testing.T) {
+	output := captureReport(t)
+
+	if err := writeJsonReport(testReportResults()); err != nil {
+		t.Fatalf("writeJsonReport() error = %v", err)
+	}
+
+	expected := `{
+  "violations": [
+    {
+      "rule_id": "format-usage",
+      "severity": "warning",
+      "file": "main.tf",
+      "start_line": 2,
+      "start_column": 7,
+      "end_line": 2,
+      "end_column": 29,
+      "address": "locals.a",
+      "text": "format(\"%s-x\", var.a)",
+      "replacement": "\"${var.a}-x\""
+    },
+    {
+      "rule_id": "unused-variable",
+      "severity": "error",
+      "file": "modules/m/variables.tf",
+      "start_line": 1,
+      "start_column": 1,
+      "end_line": 3,
+      "end_column": 2,
+      "address": "variable.b",
+      "text": "variable \"b\"",
+      "replacement": ""
+    },
+    {
+      "rule_id": "unused-variable",
+      "severity": "error",
+      "file": "modules/m/variables.tf",
+      "start_line": 5,
+      "start_column": 1,
+      "end_line": 5,
+      "end_column": 16,
+      "address": "variable.c",
+      "text": "variable \"c\"",
+      "note": "cannot be fixed, since it is assigned by an installed module"
+    }
+  ]
+}
+`
+	if output.String() != expected {
+		t.Errorf("writeJsonReport() =\n%s\nwant:\n%s", output, expected)
+	}
+}
+
+func TestWriteJsonReportWithoutViolations(t *testing.T) {
+	output := captureReport(t)
+
+	if err := writeJsonReport(nil); err != nil {
+		t.Fatalf("writeJsonReport() error = %v", err)
+	}
+
+	// consumers can rely on the list being present
+	expected := "{\n  \"violations\": []\n}\n"
+	if output.String() != expected {
+		t.Errorf("writeJsonReport() =\n%s\nwant:\n%s", output, expected)
+	}
+}
@@ -1,27 +1,79 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"sort"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
 
+const version = "0.1.0"
+
 var rootCmd = &cobra.Command{
 	Use:   "tfcleanup",
-	Short: "Simple CLI to clean some obvious things in terraform files (version=0.1.0)",
+	Short: "Simple CLI to clean some obvious things in terraform files (version=" + version + ")",
 }
 
+const (
+	exitCodeError      = 1
+	exitCodeViolations = 2
+)
+
+// errViolationsFound signals that the command ran fine, but found violations
+var errViolationsFound = errors.New("violations found")
+
+// errFilesSkipped signals that the command ran, but skipped the files that could not be parsed
+var errFilesSkipped = errors.New("files could not be parsed")
+
 func Execute() {
-	err := rootCmd.Execute()
-	if err != nil {
-		os.Exit(1)
+	if code := exitCode(rootCmd.Execute()); code != 0 {
+		os.Exit(code)
+	}
+}
+
+// exitCode returns the exit code for the error that the command returned, where skipped files are an error, as the
+// violations in them are not reported
+func exitCode(err error) int {
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, errViolationsFound):
+		return exitCodeViolations
+	default:
+		return exitCodeError
 	}
 }
 
 var targetDir string
 var verbose bool
+var recursive bool
+var excludePatterns []string
 
 func init() {
 	rootCmd.PersistentFlags().StringVarP(&targetDir, "target-dir", "t", "", "target dir (default is current working directory)")
+	rootCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "", "config file (default is "+configFilename+" in the target dir, or any of its parent dirs)")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Print verbose output")
+	rootCmd.PersistentFlags().BoolVarP(&recursive, "recursive", "R", false, "Discover TF files in all subdirectories of the target dir")
+	rootCmd.PersistentFlags().StringSliceVar(&excludePatterns, "exclude", nil, "Exclude paths matching the given glob patterns (in .gitignore format)")
+	rootCmd.PersistentFlags().StringSliceVar(&varFiles, "var-file", nil, "Set the variables of the root module in the target dir from the given .tfvars files")
+}
+
+// skippedFilesError returns an error listing the files that are skipped, if any, so the command fails after
+// processing the other files
+func skippedFilesError(cmd *cobra.Command) error {
+	if len(skippedFiles) == 0 {
+		return nil
+	}
+
+	var filenames []string
+	for filename := range skippedFiles {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+
+	cmd.SilenceUsage = true
+	return fmt.Errorf("%w: %v", errFilesSkipped, strings.Join(filenames, ", "))
 }
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExitCodeWithUnparseableFile(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		files    map[string]string
+		expected int
+	}{
+		{
+			name:     "check with violations",
+			args:     []string{"check"},
+			files:    map[string]string{"good.tf": "variable \"a\" {\n  type = string\n}\n\nlocals {\n  a = format(\"%s-x\", var.a)\n}\n"},
+			expected: exitCodeViolations,
+		},
+		{
+			name: "check with violations and unparseable file",
+			args: []string{"check"},
+			files: map[string]string{
+				"good.tf": "variable \"a\" {\n  type = string\n}\n\nlocals {\n  a = format(\"%s-x\", var.a)\n}\n",
+				"bad.tf":  "locals {\n  a = format(\"%s\",\n",
+			},
+			expected: exitCodeError,
+		},
+		{
+			name:     "check with only unparseable file",
+			args:     []string{"check"},
+			files:    map[string]string{"bad.tf": "locals {\n  a = format(\"%s\",\n"},
+			expected: exitCodeError,
+		},
+		{
+			name: "fix with unparseable file",
+			args: []string{"fix"},
+			files: map[string]string{
+				"good.tf": "variable \"a\" {\n  type = string\n}\n\nlocals {\n  a = format(\"%s-x\", var.a)\n}\n",
+				"bad.tf":  "locals {\n  a = format(\"%s\",\n",
+			},
+			expected: exitCodeError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTestFiles(t, dir, tc.files)
+			chdirForTest(t, dir)
+
+			rootCmd.SetArgs(tc.args)
+			if code := exitCode(rootCmd.Execute()); code != tc.expected {
+				t.Errorf("exit code of %v = %d; want %d", tc.args, code, tc.expected)
+			}
+		})
+	}
+}
+
+func TestFixContinuesWithUnparseableFile(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"good.tf": "variable \"a\" {\n  type = string\n}\n\nlocals {\n  a = format(\"%s-x\", var.a)\n}\n",
+		"bad.tf":  "locals {\n  a = format(\"%s\",\n",
+	})
+	chdirForTest(t, dir)
+
+	rootCmd.SetArgs([]string{"fix"})
+	if code := exitCode(rootCmd.Execute()); code != exitCodeError {
+		t.Errorf("exit code of fix = %d; want %d", code, exitCodeError)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "good.tf"))
+	if err != nil {
+		t.Fatalf("failed to read good.tf: %v", err)
+	}
+	if !strings.Contains(string(content), `"${var.a}-x"`) {
+		t.Errorf("fix resulted in:\n%s\nwant the parseable file to be fixed", content)
+	}
+}
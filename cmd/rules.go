@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/spf13/cobra"
+)
+
+type severity int
+
+const (
+	severityInfo severity = iota
+	severityWarning
+	severityError
+)
+
+var severityNames = []string{"info", "warning", "error"}
+
+// rule is a single cleanup that can be checked, and optionally fixed
+type rule struct {
+	id          string
+	name        string // plural noun used in the report, e.g. "format() usages"
+	description string
+	severity    severity
+	options     []string // names of the rule specific options that can be set in the config file
+
+	check func(tfFiles []string) ([]violation, error)
+	fix   func(tfFiles []string) error
+}
+
+// violation is a single finding of a rule, grouped for reporting by module or file
+type violation struct {
+	group   string
+	address hclAddress
+	rng     hcl.Range
+	text    string
+
+	// replacement is the source text that replaces the text at rng when fixed, or nil when there is no fix
+	replacement *string
+	// note explains the violation further, e.g. why there is no fix
+	note string
+}
+
+var registeredRules []rule
+
+var enabledRules []string
+var disabledRules []string
+
+func registerRule(r rule) {
+	registeredRules = append(registeredRules, r)
+}
+
+func addRuleFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&enabledRules, "enable", nil, "Only run the given rules (comma separated rule IDs)")
+	cmd.Flags().StringSliceVar(&disabledRules, "disable", nil, "Skip the given rules (comma separated rule IDs)")
+}
+
+func (s severity) String() string {
+	return severityNames[s]
+}
+
+func parseSeverity(s string) (severity, error) {
+	for i, name := range severityNames {
+		if name == s {
+			return severity(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown severity '%v' (expected one of: %v)", s, strings.Join(severityNames, ", "))
+}
+
+func getRule(id string) (rule, bool) {
+	for _, r := range registeredRules {
+		if r.id == id {
+			return r, true
+		}
+	}
+	return rule{}, false
+}
+
+// getSelectedRules returns the registered rules, narrowed down by the --enable and --disable flags
+func getSelectedRules() ([]rule, error) {
+	for _, id := range append(enabledRules, disabledRules...) {
+		if _, exists := getRule(id); !exists {
+			return nil, fmt.Errorf("unknown rule '%v' (known rules: %v)", id, strings.Join(ruleIds(), ", "))
+		}
+	}
+
+	var selected []rule
+	for _, r := range registeredRules {
+		if len(enabledRules) > 0 && !contains(enabledRules, r.id) {
+			continue
+		}
+		if contains(disabledRules, r.id) {
+			continue
+		}
+		selected = append(selected, r)
+	}
+
+	return selected, nil
+}
+
+func ruleIds() []string {
+	var ids []string
+	for _, r := range registeredRules {
+		ids = append(ids, r.id)
+	}
+	return ids
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetSelectedRules(t *testing.T) {
+	allExcept := func(ids ...string) []string {
+		var result []string
+		for _, id := range ruleIds() {
+			if !contains(ids, id) {
+				result = append(result, id)
+			}
+		}
+		return result
+	}
+
+	testCases := []struct {
+		name        string
+		enabled     []string
+		disabled    []string
+		expected    []string
+		expectedErr bool
+	}{
+		{name: "all rules by default", expected: ruleIds()},
+		{name: "enabled rules in registration order", enabled: []string{"join-usage", "format-usage"}, expected: []string{"format-usage", "join-usage"}},
+		{name: "disabled rule", disabled: []string{"join-usage"}, expected: allExcept("join-usage")},
+		{name: "enabled and disabled rules", enabled: []string{"format-usage", "join-usage"}, disabled: []string{"join-usage"}, expected: []string{"format-usage"}},
+		{name: "disable wins over enable", enabled: []string{"join-usage"}, disabled: []string{"join-usage"}, expected: nil},
+		{name: "unknown enabled rule", enabled: []string{"format-usage", "no-such-rule"}, expectedErr: true},
+		{name: "unknown disabled rule", disabled: []string{"no-such-rule"}, expectedErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			enabledRules, disabledRules = tc.enabled, tc.disabled
+			defer func() { enabledRules, disabledRules = nil, nil }()
+
+			selected, err := getSelectedRules()
+			if (err != nil) != tc.expectedErr {
+				t.Fatalf("getSelectedRules() error = %v; want error: %t", err, tc.expectedErr)
+			}
+
+			var result []string
+			for _, r := range selected {
+				result = append(result, r.id)
+			}
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Errorf("getSelectedRules() = %v; want %v", result, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// minimal model of the SARIF 2.1.0 format, see https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string                     `json:"name"`
+	Version        string                     `json:"version"`
+	InformationUri string                     `json:"informationUri"`
+	Rules          []sarifReportingDescriptor `json:"rules"`
+}
+
+type sarifReportingDescriptor struct {
+	Id                   string             `json:"id"`
+	Name                 string             `json:"name"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleId    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+	Fixes     []sarifFix      `json:"fixes,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	Uri string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
+}
+
+type sarifFix struct {
+	Description     sarifMessage          `json:"description"`
+	ArtifactChanges []sarifArtifactChange `json:"artifactChanges"`
+}
+
+type sarifArtifactChange struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Replacements     []sarifReplacement    `json:"replacements"`
+}
+
+type sarifReplacement struct {
+	DeletedRegion   sarifRegion   `json:"deletedRegion"`
+	InsertedContent *sarifMessage `json:"insertedContent,omitempty"`
+}
+
+var sarifLevels = map[severity]string{
+	severityInfo:    "note",
+	severityWarning: "warning",
+	severityError:   "error",
+}
+
+func writeSarifReport(results []ruleResult) error {
+	driver := sarifDriver{
+		Name:           "tfcleanup",
+		Version:        version,
+		InformationUri: "https://github.com/tiesmaster/tfcleanup",
+		Rules:          []sarifReportingDescriptor{},
+	}
+	run := sarifRun{Results: []sarifResult{}}
+
+	for i, res := range results {
+		driver.Rules = append(driver.Rules, sarifReportingDescriptor{
+			Id:                   res.rule.id,
+			Name:                 res.rule.name,
+			ShortDescription:     sarifMessage{res.rule.description},
+			DefaultConfiguration: sarifConfiguration{sarifLevels[res.rule.severity]},
+		})
+
+		for _, v := range res.violations {
+			run.Results = append(run.Results, toSarifResult(res.rule, i, v))
+		}
+	}
+	run.Tool = sarifTool{driver}
+
+	log := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	}
+
+	encoder := json.NewEncoder(reportOutput)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(log)
+}
+
+func toSarifResult(r rule, ruleIndex int, v violation) sarifResult {
+	artifact := sarifArtifactLocation{filepath.ToSlash(v.rng.Filename)}
+
+	message := fmt.Sprintf("%v: %v", r.description, v.text)
+	if v.note != "" {
+		message += " (" + v.note + ")"
+	}
+
+	result := sarifResult{
+		RuleId:    r.id,
+		RuleIndex: ruleIndex,
+		Level:     sarifLevels[r.severity],
+		Message:   sarifMessage{message},
+		Locations: []sarifLocation{{sarifPhysicalLocation{artifact, toSarifRegion(v.rng)}}},
+	}
+
+	if v.replacement != nil {
+		replacement := sarifReplacement{DeletedRegion: toSarifRegion(v.rng)}
+		if *v.replacement != "" {
+			replacement.InsertedContent = &sarifMessage{*v.replacement}
+		}
+
+		result.Fixes = []sarifFix{{
+			Description:     sarifMessage{fmt.Sprintf("Fix %v", r.id)},
+			ArtifactChanges: []sarifArtifactChange{{artifact, []sarifReplacement{replacement}}},
+		}}
+	}
+
+	return result
+}
+
+func toSarifRegion(r hcl.Range) sarifRegion {
+	return sarifRegion{
+		StartLine:   r.Start.Line,
+		StartColumn: r.Start.Column,
+		EndLine:     r.End.Line,
+		EndColumn:   r.End.Column,
+	}
+}
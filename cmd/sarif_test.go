@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+func TestWriteSarifReport(t *testing.T) {
+	output := captureReport(t)
+
+	if err := writeSarifReport(testReportResults()); err != nil {
+		t.Fatalf("writeSarifReport() error = %v", err)
+	}
+
+	// the regions are 1-based, and a removal is a replacement without inserted content
+	expected := `{
+  "$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+  "version": "2.1.0",
+  "runs": [
+    {
+      "tool": {
+        "driver": {
+          "name": "tfcleanup",
+          "version": "0.1.0",
+          "informationUri": "https://github.com/tiesmaster/tfcleanup",
+          "rules": [
+            {
+              "id": "format-usage",
+              "name": "format() usages",
+              "shortDescription": {
+                "text": "Usages of format() that can be a template"
+              },
+              "defaultConfiguration": {
+                "level": "warning"
+              }
+            },
+            {
+              "id": "unused-variable",
+              "name": "unused variables",
+              "shortDescription": {
+                "text": "Variables that are never referenced in their module"
+              },
+              "defaultConfiguration": {
+                "level": "error"
+              }
+            }
+          ]
+        }
+      },
+      "results": [
+        {
+          "ruleId": "format-usage",
+          "ruleIndex": 0,
+          "level": "warning",
+          "message": {
+            "text": "Usages of format() that can be a template: format(\"%s-x\", var.a)"
+          },
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {
+                  "uri": "main.tf"
+                },
+                "region": {
+                  "startLine": 2,
+                  "startColumn": 7,
+                  "endLine": 2,
+                  "endColumn": 29
+                }
+              }
+            }
+          ],
+          "fixes": [
+            {
+              "description": {
+                "text": "Fix format-usage"
+              },
+              "artifactChanges": [
+                {
+                  "artifactLocation": {
+                    "uri": "main.tf"
+                  },
+                  "replacements": [
+                    {
+                      "deletedRegion": {
+                        "startLine": 2,
+                        "startColumn": 7,
+                        "endLine": 2,
+                        "endColumn": 29
+                      },
+                      "insertedContent": {
+                        "text": "\"${var.a}-x\""
+                      }
+                    }
+                  ]
+                }
+              ]
+            }
+          ]
+        },
+        {
+          "ruleId": "unused-variable",
+          "ruleIndex": 1,
+          "level": "error",
+          "message": {
+            "text": "Variables that are never referenced in their module: variable \"b\""
+          },
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {
+                  "uri": "modules/m/variables.tf"
+                },
+                "region": {
+                  "startLine": 1,
+                  "startColumn": 1,
+                  "endLine": 3,
+                  "endColumn": 2
+                }
+              }
+            }
+          ],
+          "fixes": [
+            {
+              "description": {
+                "text": "Fix unused-variable"
+              },
+              "artifactChanges": [
+                {
+                  "artifactLocation": {
+                    "uri": "modules/m/variables.tf"
+                  },
+                  "replacements": [
+                    {
+                      "deletedRegion": {
+                        "startLine": 1,
+                        "startColumn": 1,
+                        "endLine": 3,
+                        "endColumn": 2
+                      }
+                    }
+                  ]
+                }
+              ]
+            }
+          ]
+        },
+        {
+          "ruleId": "unused-variable",
+          "ruleIndex": 1,
+          "level": "error",
+          "message": {
+            "text": "Variables that are never referenced in their module: variable \"c\" (cannot be fixed, since it is assigned by an installed module)"
+          },
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {
+                  "uri": "modules/m/variables.tf"
+                },
+                "region": {
+                  "startLine": 5,
+                  "startColumn": 1,
+                  "endLine": 5,
+                  "endColumn": 16
+                }
+              }
+            }
+          ]
+        }
+      ]
+    }
+  ]
+}
+`
+	if output.String() != expected {
+		t.Errorf("writeSarifReport() =\n%s\nwant:\n%s", output, expected)
+	}
+}
+
+func TestToSarifResultLevel(t *testing.T) {
+	testCases := []struct {
+		severity severity
+		expected string
+	}{
+		{severity: severityInfo, expected: "note"},
+		{severity: severityWarning, expected: "warning"},
+		{severity: severityError, expected: "error"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expected, func(t *testing.T) {
+			r := rule{id: "format-usage", severity: tc.severity}
+			result := toSarifResult(r, 0, violation{rng: hcl.Range{Filename: "main.tf"}})
+			if result.Level != tc.expected {
+				t.Errorf("level of severity %v = %s; want %s", tc.severity, result.Level, tc.expected)
+			}
+			if result.Fixes != nil {
+				t.Errorf("fixes of violation without replacement = %+v; want none", result.Fixes)
+			}
+		})
+	}
+}
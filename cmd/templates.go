@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// templatePart is a part of a string template, either a literal, or the source of an interpolated expression
+type templatePart struct {
+	literal string
+	expr    string
+}
+
+// buildQuotedTemplate returns the source of a quoted template with the given parts, that evaluates to the literals
+// and the interpolated expressions
+func buildQuotedTemplate(parts []templatePart) string {
+	return `"` + buildTemplateContent(parts, escapeQuotedTemplateLiteral) + `"`
+}
+
+// heredoc is the way a heredoc template is written, e.g. "<<-EOT" with its lines indented by two spaces
+type heredoc struct {
+	opener string
+	marker string
+	indent string
+}
+
+// parseHeredoc returns how the expression is written, when it is a heredoc template
+func parseHeredoc(expr hclsyntax.Expression, src []byte) (heredoc, bool) {
+	exprSrc := string(expr.Range().SliceBytes(src))
+	firstLineEnd := strings.IndexByte(exprSrc, '\n')
+	if !strings.HasPrefix(exprSrc, "<<") || firstLineEnd == -1 {
+		return heredoc{}, false
+	}
+
+	h := heredoc{opener: strings.TrimSpace(exprSrc[:firstLineEnd])}
+	h.marker = strings.TrimLeft(h.opener, "<-")
+
+	if strings.HasPrefix(h.opener, "<<-") {
+		// the lines are indented like the closing marker
+		lastLine := exprSrc[strings.LastIndexByte(exprSrc, '\n')+1:]
+		h.indent = lastLine[:len(lastLine)-len(strings.TrimLeft(lastLine, " \t"))]
+	}
+	return h, true
+}
+
+// buildHeredocTemplate returns the source of a heredoc template with the given parts, written in the same way as the
+// given heredoc, or as a plain heredoc, when the indentation would not be stripped the same way
+func buildHeredocTemplate(parts []templatePart, h heredoc) (string, bool) {
+	content := buildTemplateContent(parts, escapeHeredocLiteral)
+	if !strings.HasSuffix(content, "\n") {
+		return "", false
+	}
+
+	candidates := []heredoc{h, {opener: "<<" + h.marker, marker: h.marker}}
+	for _, c := range candidates {
+		var sb strings.Builder
+		sb.WriteString(c.opener + "\n")
+		for _, line := range strings.SplitAfter(strings.TrimSuffix(content, "\n"), "\n") {
+			if strings.TrimSpace(line) != "" {
+				sb.WriteString(c.indent)
+			}
+			sb.WriteString(line)
+		}
+		// the newline after the closing marker separates it from anything that follows
+		sb.WriteString("\n" + c.indent + c.marker + "\n")
+
+		if isSameTemplate(sb.String(), parts) {
+			return sb.String(), true
+		}
+	}
+	return "", false
+}
+
+// isSameTemplate returns whether the source of the template parses to the given parts
+func isSameTemplate(src string, parts []templatePart) bool {
+	expr, diags := hclsyntax.ParseExpression([]byte(src), "", hcl.InitialPos)
+	if diags.HasErrors() {
+		return false
+	}
+
+	template, ok := expr.(*hclsyntax.TemplateExpr)
+	if !ok {
+		return false
+	}
+	parsedParts, ok := templateExprParts(template, []byte(src))
+	if !ok {
+		return false
+	}
+
+	return reflect.DeepEqual(normalizeTemplateParts(parsedParts), normalizeTemplateParts(parts))
+}
+
+// normalizeTemplateParts merges the adjacent literals, where string literal interpolations like ${"$"} count as
+// literals too
+func normalizeTemplateParts(parts []templatePart) []templatePart {
+	var result []templatePart
+	for _, part := range parts {
+		if literal, ok := decodeStringLiteral(part.expr); ok {
+			part = templatePart{literal: literal}
+		}
+
+		if part.expr == "" && len(result) > 0 && result[len(result)-1].expr == "" {
+			result[len(result)-1].literal += part.literal
+			continue
+		}
+		result = append(result, part)
+	}
+	return result
+}
+
+// buildTemplateContent returns the content of a template with the given parts, where the literals are escaped with the
+// escape function for the kind of template
+func buildTemplateContent(parts []templatePart, escape func(literal string) string) string {
+	var sb strings.Builder
+
+	var literal string
+	for _, part := range parts {
+		if part.expr == "" {
+			literal += part.literal
+			continue
+		}
+
+		prefix, dollars := splitTrailingDollars(literal)
+		sb.WriteString(escape(prefix))
+		if dollars != "" {
+			sb.WriteString(`${"` + dollars + `"}`)
+		}
+		literal = ""
+
+		sb.WriteString("${" + part.expr + "}")
+	}
+	sb.WriteString(escape(literal))
+
+	return sb.String()
+}
+
+// splitTrailingDollars splits off the dollar signs at the end of a literal, as these cannot be written right before an
+// interpolation, since "$${" is the escape sequence for a literal "${"
+func splitTrailingDollars(literal string) (string, string) {
+	prefix := strings.TrimRight(literal, "$")
+	return prefix, literal[len(prefix):]
+}
+
+// escapeQuotedTemplateLiteral escapes the literal for use in a quoted template, so that it evaluates to the literal
+// again, see https://github.com/hashicorp/hcl/blob/main/hclsyntax/spec.md#template-literals
+func escapeQuotedTemplateLiteral(literal string) string {
+	var sb strings.Builder
+	for i, r := range literal {
+		switch {
+		case r == '\\':
+			sb.WriteString(`\\`)
+		case r == '"':
+			sb.WriteString(`\"`)
+		case r == '\n':
+			sb.WriteString(`\n`)
+		case r == '\r':
+			sb.WriteString(`\r`)
+		case r == '\t':
+			sb.WriteString(`\t`)
+		case (r == '$' || r == '%') && strings.HasPrefix(literal[i+1:], "{"):
+			// escape the start of an interpolation or directive
+			sb.WriteRune(r)
+			sb.WriteRune(r)
+		case r < 0x20:
+			sb.WriteString(fmt.Sprintf(`\u%04x`, r))
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// escapeHeredocLiteral escapes the literal for use in a heredoc template, where only the start of an interpolation or
+// directive needs escaping
+func escapeHeredocLiteral(literal string) string {
+	literal = strings.ReplaceAll(literal, "${", "$${")
+	return strings.ReplaceAll(literal, "%{", "%%{")
+}
+
+// stringLiteralValue returns the value of the expression, when it is a string without any interpolation
+func stringLiteralValue(expr hclsyntax.Expression) (string, bool) {
+	template, ok := expr.(*hclsyntax.TemplateExpr)
+	if !ok || !template.IsStringLiteral() {
+		return "", false
+	}
+
+	val, diags := template.Value(nil)
+	if diags.HasErrors() || !val.IsKnown() || val.IsNull() {
+		return "", false
+	}
+	return val.AsString(), true
+}
+
+// decodeStringLiteral returns the value of the source of a quoted string, when it has no interpolation
+func decodeStringLiteral(src string) (string, bool) {
+	expr, diags := hclsyntax.ParseExpression([]byte(src), "", hcl.InitialPos)
+	if diags.HasErrors() {
+		return "", false
+	}
+	return stringLiteralValue(expr)
+}
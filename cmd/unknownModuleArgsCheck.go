@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/agext/levenshtein"
+)
+
+// unknownModuleArgument is an assignment in a module call to a variable that the module doesn't declare
+type unknownModuleArgument struct {
+	mod    module
+	assign expression
+
+	// suggestion is the declared variable that is closest to the name of the assignment, if any
+	suggestion string
+}
+
+func init() {
+	registerRule(rule{
+		id:          "unknown-module-argument",
+		name:        "unknown module arguments",
+		description: "Module assignments to variables that the module doesn't declare",
+		severity:    severityError,
+		check:       checkUnknownModuleArguments,
+	})
+}
+
+func checkUnknownModuleArguments(tfFiles []string) ([]violation, error) {
+	unknownArgs, err := checkForUnknownModuleArguments(tfFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []violation
+	for _, arg := range unknownArgs {
+		v := violation{
+			group:   arg.mod.description(),
+			address: arg.mod.hclAddress(arg.assign.name()),
+			rng:     arg.assign.attr.Range(),
+			text:    sourceText(arg.assign.attr.Range()),
+		}
+		if arg.suggestion != "" {
+			v.note = fmt.Sprintf("did you mean '%v'?", arg.suggestion)
+		}
+		violations = append(violations, v)
+	}
+
+	return violations, nil
+}
+
+func checkForUnknownModuleArguments(tfFiles []string) ([]unknownModuleArgument, error) {
+	referencedModules, err := getModuleCallTree(tfFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []unknownModuleArgument
+	for _, mod := range referencedModules {
+		if mod.isInstalled() {
+			// the module calls of installed modules cannot be fixed here
+			continue
+		}
+
+		moduleDir, err := resolveModuleDir(mod)
+		if err != nil {
+			return nil, err
+		}
+		if moduleDir == "" {
+			// the variables of the module are unknown
+			continue
+		}
+
+		skipped, err := hasSkippedFiles(moduleDir)
+		if err != nil {
+			return nil, err
+		}
+		if skipped {
+			// the arguments might be declared in the skipped files, which already fail the check
+			continue
+		}
+
+		moduleVariables, err := getModuleVariables(mod)
+		if err != nil {
+			return nil, err
+		}
+		moduleVariablesMap := toMap(moduleVariables)
+
+		var names []string
+		for _, v := range moduleVariables {
+			names = append(names, v.name())
+		}
+
+		for name, assign := range filterForTerraformAssignments(getVariableAssignments(mod)) {
+			if _, exists := moduleVariablesMap[name]; !exists {
+				result = append(result, unknownModuleArgument{mod, assign, nameSuggestion(name, names)})
+			}
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].assign.attr.SrcRange.Start.Byte < result[j].assign.attr.SrcRange.Start.Byte
+	})
+	return result, nil
+}
+
+// nameSuggestion returns the name that is closest to the given name, when it is close enough to likely be a typo, like
+// HCL does for its "Did you mean" suggestions
+func nameSuggestion(given string, names []string) string {
+	suggestion := ""
+	bestDistance := 3
+	for _, name := range names {
+		if distance := levenshtein.Distance(given, name, nil); distance < bestDistance {
+			suggestion = name
+			bestDistance = distance
+		}
+	}
+	return suggestion
+}
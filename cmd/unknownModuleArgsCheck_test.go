@@ -0,0 +1,45 @@
+package cmd
+
+import "testing"
+
+func TestNameSuggestion(t *testing.T) {
+	names := []string{"name", "instance_type", "subnet_ids"}
+	testCases := []struct {
+		given    string
+		expected string
+	}{
+		{given: "nmae", expected: "name"},
+		{given: "instance_typ", expected: "instance_type"},
+		{given: "subnets_id", expected: "subnet_ids"},
+		{given: "subnet_id", expected: "subnet_ids"},
+		{given: "something_else", expected: ""},
+	}
+
+	for _, tc := range testCases {
+		result := nameSuggestion(tc.given, names)
+		if result != tc.expected {
+			t.Errorf("nameSuggestion(%s) = %s; want %s", tc.given, result, tc.expected)
+		}
+	}
+}
+
+func TestUnknownModuleArgumentsWithSkippedModuleFile(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"main.tf":        "module \"m\" {\n  source = \"./modules/m\"\n  size   = 2\n  nmae   = \"x\"\n}\n\nmodule \"n\" {\n  source = \"./modules/n\"\n  nmae   = \"x\"\n}\n",
+		"modules/m/a.tf": "variable \"name\" {}\n",
+		"modules/m/b.tf": "variable \"size\" {\n",
+		"modules/n/a.tf": "variable \"name\" {}\n",
+	})
+	chdirForTest(t, dir)
+
+	unknownArgs, err := checkForUnknownModuleArguments([]string{"main.tf"})
+	if err != nil {
+		t.Fatalf("checkForUnknownModuleArguments() error = %v", err)
+	}
+
+	// the module with the skipped file cannot be verified, so only the other module is reported
+	if len(unknownArgs) != 1 || unknownArgs[0].mod.name() != "n" || unknownArgs[0].assign.name() != "nmae" {
+		t.Errorf("checkForUnknownModuleArguments() = %+v; want only 'nmae' of module 'n'", unknownArgs)
+	}
+}
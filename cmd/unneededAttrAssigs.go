@@ -1,15 +1,67 @@
 package cmd
 
 import (
-	"fmt"
+	"path"
 
+	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclwrite"
 )
 
 type unneededAttrAssigs map[module][]expression
 
+func init() {
+	registerRule(rule{
+		id:          "unneeded-module-assignment",
+		name:        "unneeded module assignments",
+		description: "Module assignments that are equal to the default value of the variable in the module",
+		severity:    severityWarning,
+		check:       checkUnneededAttributeAssignments,
+		fix:         performUnneededAttrFix,
+	})
+}
+
+// checks for attribute assignments with default values of variable from their module
+func checkUnneededAttributeAssignments(tfFiles []string) ([]violation, error) {
+	report, err := checkForUnneededAttributeAssignments(tfFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []violation
+	for mod, unneededAssigns := range report {
+		for _, assign := range unneededAssigns {
+			v := violation{
+				group:   mod.description(),
+				address: mod.hclAddress(assign.name()),
+				rng:     assign.attr.Range(),
+				text:    sourceText(assign.attr.Range()),
+			}
+			if !mod.isInstalled() {
+				removal := ""
+				v.replacement = &removal
+			}
+			violations = append(violations, v)
+		}
+	}
+
+	return violations, nil
+}
+
+func performUnneededAttrFix(tfFiles []string) error {
+	report, err := checkForUnneededAttributeAssignments(tfFiles)
+	if err != nil {
+		return err
+	}
+
+	err = removeUnneededAttributes(report)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
 func checkForUnneededAttributeAssignments(files []string) (unneededAttrAssigs, error) {
-	referencedModules, err := getReferencedModules(files)
+	referencedModules, err := getModuleCallTree(files)
 	if err != nil {
 		return nil, err
 	}
@@ -20,7 +72,12 @@ func checkForUnneededAttributeAssignments(files []string) (unneededAttrAssigs, e
 	}
 
 	for _, mod := range referencedModules {
-		unneededAssignments, err := checkForUnneededAssignments(mod)
+		ctx, err := getModuleEvalContext(path.Dir(mod.filename()), referencedModules)
+		if err != nil {
+			return nil, err
+		}
+
+		unneededAssignments, err := checkForUnneededAssignments(mod, ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -31,7 +88,9 @@ func checkForUnneededAttributeAssignments(files []string) (unneededAttrAssigs, e
 	return m, nil
 }
 
-func checkForUnneededAssignments(module module) ([]expression, error) {
+// checkForUnneededAssignments returns the assignments of the module call, that are equal to the default value of the
+// variable, where the assigned values are evaluated with the context of the calling module
+func checkForUnneededAssignments(module module, ctx *hcl.EvalContext) ([]expression, error) {
 	moduleVariables, err := getModuleVariables(module)
 	if err != nil {
 		return nil, err
@@ -42,22 +101,23 @@ func checkForUnneededAssignments(module module) ([]expression, error) {
 
 	variableAssignments = filterForTerraformAssignments(variableAssignments)
 
-
 	var unneededAssignments []expression
 	for varName, assignExpr := range variableAssignments {
-		if varDefinition, exists := moduleVariablesMap[varName]; exists && equalToVariableDefinition(assignExpr, varDefinition) {
+		if varDefinition, exists := moduleVariablesMap[varName]; exists && equalToVariableDefinition(assignExpr, ctx, varDefinition) {
 			unneededAssignments = append(unneededAssignments, assignExpr)
-		} else if !exists && verbose {
-			fmt.Printf("WARNING: module assignment not found as variable in referenced module '%v': %v\n", module.name(), varName)
 		}
 	}
 
 	return unneededAssignments, nil
 }
 
+// moduleMetaArguments are the arguments of a module call that are handled by terraform, instead of being variables
+var moduleMetaArguments = []string{"source", "version", "count", "for_each", "providers", "depends_on"}
+
 func filterForTerraformAssignments(variableAssignments map[string]expression) map[string]expression {
-	delete(variableAssignments, "source")
-	delete(variableAssignments, "version")
+	for _, name := range moduleMetaArguments {
+		delete(variableAssignments, name)
+	}
 
 	return variableAssignments
 }
@@ -76,6 +136,10 @@ func removeUnneededAttributes(report unneededAttrAssigs) error {
 		if len(unneededAssign) == 0 {
 			continue
 		}
+		if mod.isInstalled() {
+			// installed modules are overwritten by `terraform init`, so fixing them is pointless
+			continue
+		}
 		err := removeUnneededAttributesFromModule(mod, unneededAssign)
 		if err != nil {
 			return err
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func TestUnneededAttributeAssignmentsInSharedModule(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, sharedModuleFiles)
+	chdirForTest(t, dir)
+
+	violations, err := checkUnneededAttributeAssignments([]string{"main.tf"})
+	if err != nil {
+		t.Fatalf("checkUnneededAttributeAssignments() error = %v", err)
+	}
+
+	// the shared module is called twice, but its call of the leaf module is reported once
+	if len(violations) != 1 || violations[0].text != "size   = 1" {
+		t.Fatalf("checkUnneededAttributeAssignments() = %+v; want only 'size   = 1'", violations)
+	}
+}
+
+func TestUnneededAttributeAssignmentsWithRootVariableDefault(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"main.tf": `variable "enable_nat" {
+  type    = bool
+  default = false
+}
+
+module "net" {
+  source     = "./modules/net"
+  enable_nat = var.enable_nat
+}
+
+terraform {
+  backend "s3" {}
+}
+`,
+		"modules/net/main.tf": "variable \"enable_nat\" {\n  type    = bool\n  default = false\n}\n",
+	})
+	chdirForTest(t, dir)
+
+	// the default of the root variable can be overridden with -var or TF_VAR_enable_nat
+	violations, err := checkUnneededAttributeAssignments([]string{"main.tf"})
+	if err != nil {
+		t.Fatalf("checkUnneededAttributeAssignments() error = %v", err)
+	}
+	if len(violations) != 0 {
+		t.Errorf("checkUnneededAttributeAssignments() = %+v; want none", violations)
+	}
+
+	dryRun = true
+	defer func() { dryRun = false }()
+	if err = performUnneededAttrFix([]string{"main.tf"}); err != nil {
+		t.Fatalf("performUnneededAttrFix() error = %v", err)
+	}
+	if content, exists := pendingFiles["main.tf"]; exists {
+		t.Errorf("performUnneededAttrFix() changed main.tf into:\n%s", content)
+	}
+}
+
+func TestUnneededAttributeAssignmentsRecursiveWithNestedRegistryModule(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"main.tf":             "module \"net\" {\n  source = \"./modules/net\"\n}\n",
+		"modules/net/main.tf": "module \"vpc\" {\n  source     = \"terraform-aws-modules/vpc/aws\"\n  version    = \"5.0.0\"\n  enable_nat = false\n}\n",
+		".terraform/modules/modules.json": `{"Modules": [
+    {"Key": "", "Source": "", "Dir": "."},
+    {"Key": "net", "Source": "./modules/net", "Dir": "modules/net"},
+    {"Key": "net.vpc", "Source": "registry.terraform.io/terraform-aws-modules/vpc/aws", "Version": "5.0.0", "Dir": ".terraform/modules/net.vpc"}
+]}`,
+		".terraform/modules/net.vpc/variables.tf": "variable \"enable_nat\" {\n  type    = bool\n  default = false\n}\n",
+	})
+	chdirForTest(t, dir)
+	diagnostics := captureDiagnostics(t)
+
+	for _, r := range []bool{false, true} {
+		recursive = r
+		files, err := getTerraformFiles()
+		recursive = false
+		if err != nil {
+			t.Fatalf("getTerraformFiles() error = %v", err)
+		}
+
+		// the dir of the local module is no root module, so the registry module it calls is resolved with the
+		// manifest of the root module
+		violations, err := checkUnneededAttributeAssignments(files)
+		if err != nil {
+			t.Fatalf("checkUnneededAttributeAssignments() error = %v", err)
+		}
+		if len(violations) != 1 || violations[0].address.string() != "module.net.module.vpc.enable_nat" {
+			t.Errorf("checkUnneededAttributeAssignments(%v) = %+v; want only module.net.module.vpc.enable_nat", files, violations)
+		}
+	}
+
+	if diagnostics.Len() > 0 {
+		t.Errorf("unexpected diagnostics:\n%s", diagnostics)
+	}
+}
@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// unusedLocal is a local value that is never referenced in its module, or only by other unused locals
+type unusedLocal struct {
+	attr       *hclsyntax.Attribute
+	hclAddress hclAddress
+
+	// referenced tells whether the local is referenced by other unused locals
+	referenced bool
+}
+
+func init() {
+	registerRule(rule{
+		id:          "unused-local",
+		name:        "unused locals",
+		description: "Local values that are never referenced in their module",
+		severity:    severityWarning,
+		check:       checkUnusedLocals,
+		fix:         performUnusedLocalsFix,
+	})
+}
+
+// CHECK
+
+func checkUnusedLocals(tfFiles []string) ([]violation, error) {
+	var violations []violation
+	for _, dir := range getModuleDirs(tfFiles) {
+		unusedLocals, err := checkForUnusedLocalsInDir(dir, tfFiles)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, l := range unusedLocals {
+			removal := ""
+			v := violation{
+				group:       moduleDirDescription(dir),
+				address:     l.hclAddress,
+				rng:         l.attr.Range(),
+				text:        sourceText(l.attr.Range()),
+				replacement: &removal,
+			}
+			if l.referenced {
+				v.note = "only referenced by other unused locals"
+			}
+			violations = append(violations, v)
+		}
+	}
+
+	return violations, nil
+}
+
+// checkForUnusedLocalsInDir returns the locals declared in the given files of the dir, that are not referenced in any
+// of the files of the dir, other than by locals that are unused themselves
+func checkForUnusedLocalsInDir(dir string, tfFiles []string) ([]unusedLocal, error) {
+	filenames, err := getDirTerraformFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var declared []unusedLocal
+	localReferences := make(map[string][]string)
+	var used []string
+	for _, f := range filenames {
+		hclFile := parseHclFileOrSkip(f)
+		if hclFile == nil {
+			// the local might be referenced in the skipped file
+			return nil, nil
+		}
+
+		walkAttributes(hclFile.Body.(*hclsyntax.Body), nil, func(attr *hclsyntax.Attribute, address hclAddress) {
+			references := getLocalReferences(attr.Expr)
+			if len(address.blocks) == 1 && address.blocks[0].typeName == "locals" {
+				localReferences[attr.Name] = references
+				if contains(tfFiles, f) {
+					declared = append(declared, unusedLocal{attr: attr, hclAddress: address})
+				}
+			} else {
+				used = append(used, references...)
+			}
+		})
+	}
+
+	// the locals that are referenced by used locals, are used as well
+	for i := 0; i < len(used); i++ {
+		for _, name := range localReferences[used[i]] {
+			if !contains(used, name) {
+				used = append(used, name)
+			}
+		}
+	}
+
+	var result []unusedLocal
+	for _, l := range declared {
+		if contains(used, l.attr.Name) {
+			continue
+		}
+		for _, references := range localReferences {
+			if contains(references, l.attr.Name) {
+				l.referenced = true
+			}
+		}
+		result = append(result, l)
+	}
+	return result, nil
+}
+
+// getLocalReferences returns the names of the locals that are referenced as local.<name> in the expression
+func getLocalReferences(expr hclsyntax.Expression) []string {
+	var names []string
+	for _, traversal := range expr.Variables() {
+		if traversal.RootName() != "local" || len(traversal) < 2 {
+			continue
+		}
+		if attr, ok := traversal[1].(hcl.TraverseAttr); ok {
+			names = append(names, attr.Name)
+		}
+	}
+	return names
+}
+
+// FIX
+
+func performUnusedLocalsFix(tfFiles []string) error {
+	for _, dir := range getModuleDirs(tfFiles) {
+		unusedLocals, err := checkForUnusedLocalsInDir(dir, tfFiles)
+		if err != nil {
+			return err
+		}
+
+		byFile := make(map[string][]unusedLocal)
+		for _, l := range unusedLocals {
+			byFile[l.attr.SrcRange.Filename] = append(byFile[l.attr.SrcRange.Filename], l)
+		}
+
+		for filename, fileLocals := range byFile {
+			if err = removeUnusedLocals(filename, fileLocals); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// removeUnusedLocals removes the locals from the file, along with the locals blocks that become empty
+func removeUnusedLocals(filename string, unusedLocals []unusedLocal) error {
+	return patchFile(filename, func(hclFile *hclwrite.File) (*hclwrite.File, error) {
+		// look up all blocks first, as removing a block changes the index of the blocks after it
+		var blocks []*hclwrite.Block
+		for _, l := range unusedLocals {
+			blocks = append(blocks, findBlockForWrite(hclFile.Body(), l.hclAddress.blocks[0]))
+		}
+
+		for i, l := range unusedLocals {
+			if blocks[i] != nil {
+				blocks[i].Body().RemoveAttribute(l.attr.Name)
+			}
+		}
+
+		for _, bl := range blocks {
+			if bl != nil && len(bl.Body().Attributes()) == 0 && len(bl.Body().Blocks()) == 0 {
+				removeBlock(hclFile.Body(), bl)
+			}
+		}
+		return hclFile, nil
+	})
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestUnusedLocals(t *testing.T) {
+	dir := t.TempDir()
+	main := filepath.Join(dir, "main.tf")
+	writeTestFiles(t, dir, map[string]string{
+		"main.tf": `locals {
+  used    = "a"
+  helper  = "b"
+  derived = "${local.helper}-c"
+}
+
+locals {
+  unused  = local.chained
+  chained = "d"
+}
+
+output "x" {
+  value = local.used
+}
+`,
+		"other.tf": `output "y" {
+  value = local.derived
+}
+`,
+	})
+
+	unusedLocals, err := checkForUnusedLocalsInDir(dir, []string{main})
+	if err != nil {
+		t.Fatalf("checkForUnusedLocalsInDir() error = %v", err)
+	}
+
+	var names []string
+	for _, l := range unusedLocals {
+		names = append(names, l.hclAddress.string())
+	}
+	sort.Strings(names)
+	if len(names) != 2 || names[0] != "locals[1].chained" || names[1] != "locals[1].unused" {
+		t.Fatalf("checkForUnusedLocalsInDir() = %v; want the locals in the second block", names)
+	}
+
+	dryRun = true
+	defer func() {
+		dryRun = false
+		delete(pendingFiles, main)
+	}()
+
+	if err = removeUnusedLocals(main, unusedLocals); err != nil {
+		t.Fatalf("removeUnusedLocals() error = %v", err)
+	}
+
+	expected := `locals {
+  used    = "a"
+  helper  = "b"
+  derived = "${local.helper}-c"
+}
+
+output "x" {
+  value = local.used
+}
+`
+	if string(pendingFiles[main]) != expected {
+		t.Errorf("removeUnusedLocals() resulted in:\n%s\nwant:\n%s", pendingFiles[main], expected)
+	}
+}
+
+func TestRemoveUnusedLocalsBlock(t *testing.T) {
+	testCases := []struct {
+		name     string
+		src      string
+		expected string
+	}{
+		{
+			name:     "first block",
+			src:      "locals {\n  a = 1\n}\n\noutput \"x\" {\n  value = 1\n}\n",
+			expected: "output \"x\" {\n  value = 1\n}\n",
+		},
+		{
+			name:     "last block",
+			src:      "output \"x\" {\n  value = 1\n}\n\nlocals {\n  a = 1\n}\n",
+			expected: "output \"x\" {\n  value = 1\n}\n",
+		},
+		{
+			name:     "block with comment",
+			src:      "output \"x\" {\n  value = 1\n}\n\n# helpers\nlocals {\n  a = 1\n}\n\noutput \"y\" {\n  value = 2\n}\n",
+			expected: "output \"x\" {\n  value = 1\n}\n\noutput \"y\" {\n  value = 2\n}\n",
+		},
+		{
+			name:     "block without blank lines",
+			src:      "output \"x\" {\n  value = 1\n}\nlocals {\n  a = 1\n}\noutput \"y\" {\n  value = 2\n}\n",
+			expected: "output \"x\" {\n  value = 1\n}\noutput \"y\" {\n  value = 2\n}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			main := filepath.Join(dir, "main.tf")
+			writeTestFiles(t, dir, map[string]string{"main.tf": tc.src})
+
+			unusedLocals, err := checkForUnusedLocalsInDir(dir, []string{main})
+			if err != nil {
+				t.Fatalf("checkForUnusedLocalsInDir() error = %v", err)
+			}
+
+			dryRun = true
+			defer func() {
+				dryRun = false
+				delete(pendingFiles, main)
+			}()
+
+			if err = removeUnusedLocals(main, unusedLocals); err != nil {
+				t.Fatalf("removeUnusedLocals() error = %v", err)
+			}
+			if string(pendingFiles[main]) != tc.expected {
+				t.Errorf("removeUnusedLocals() resulted in:\n%s\nwant:\n%s", pendingFiles[main], tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,280 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// unusedVariable is a variable that is never referenced in its module, with the local module calls that assign it
+type unusedVariable struct {
+	variableDefinition
+	callers []module
+}
+
+func init() {
+	registerRule(rule{
+		id:          "unused-variable",
+		name:        "unused variables",
+		description: "Variables that are never referenced in their module",
+		severity:    severityWarning,
+		check:       checkUnusedVariables,
+		fix:         performUnusedVariablesFix,
+	})
+}
+
+// CHECK
+
+func checkUnusedVariables(tfFiles []string) ([]violation, error) {
+	report, err := checkForUnusedVariables(tfFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []violation
+	for dir, unusedVars := range report {
+		for _, v := range unusedVars {
+			violation := violation{
+				group:   moduleDirDescription(dir),
+				address: hclAddress{[]hclBlockId{{typeName: "variable", labels: v.bl.Labels}}, ""},
+				rng:     v.bl.Range(),
+				text:    sourceText(v.bl.DefRange()),
+			}
+			if !v.isFixable() {
+				violation.note = "cannot be fixed, since it is assigned by an installed module"
+			} else {
+				removal := ""
+				violation.replacement = &removal
+
+				if len(v.callers) > 0 {
+					var callers []string
+					for _, mod := range v.callers {
+						callers = append(callers, mod.description())
+					}
+					violation.note = "the fix also removes the assignment from " + strings.Join(callers, ", ")
+				}
+			}
+			violations = append(violations, violation)
+		}
+	}
+
+	return violations, nil
+}
+
+// checkForUnusedVariables returns the unused variables per module dir, for the dirs of the given files
+func checkForUnusedVariables(tfFiles []string) (map[string][]unusedVariable, error) {
+	moduleCalls, err := getModuleCallTree(tfFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string][]unusedVariable)
+	for _, dir := range getModuleDirs(tfFiles) {
+		unusedVars, err := checkForUnusedVariablesInDir(dir, tfFiles)
+		if err != nil {
+			return nil, err
+		}
+
+		callers, err := getLocalModuleCalls(moduleCalls, dir)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, v := range unusedVars {
+			v.callers = getAssigningModuleCalls(callers, v.name())
+			result[dir] = append(result[dir], v)
+		}
+	}
+
+	return result, nil
+}
+
+// getModuleDirs returns the dirs of the files, which each contain a single module
+func getModuleDirs(tfFiles []string) []string {
+	var dirs []string
+	for _, f := range tfFiles {
+		if dir := path.Dir(f); !contains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+func moduleDirDescription(dir string) string {
+	if dir == "." {
+		return "the root module"
+	}
+	return fmt.Sprintf("module in '%v'", dir)
+}
+
+// checkForUnusedVariablesInDir returns the variables declared in the given files of the dir, that are not referenced in
+// any of the files of the dir
+func checkForUnusedVariablesInDir(dir string, tfFiles []string) ([]unusedVariable, error) {
+	filenames, err := getDirTerraformFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var declared []variableDefinition
+	referenced := make(map[string]bool)
+	for _, f := range filenames {
+		hclFile := parseHclFileOrSkip(f)
+		if hclFile == nil {
+			// the variable might be referenced in the skipped file
+			return nil, nil
+		}
+
+		body := hclFile.Body.(*hclsyntax.Body)
+		for name := range getVariableReferences(body) {
+			referenced[name] = true
+		}
+
+		for _, bl := range body.Blocks {
+			if bl.Type == "variable" && len(bl.Labels) == 1 && contains(tfFiles, f) {
+				declared = append(declared, variableDefinition{bl})
+			}
+		}
+	}
+
+	var result []unusedVariable
+	for _, v := range declared {
+		if !referenced[v.name()] {
+			result = append(result, unusedVariable{variableDefinition: v})
+		}
+	}
+	return result, nil
+}
+
+// getVariableReferences returns the names of the variables that are referenced as var.<name> in the body, where a
+// reference of a variable to itself, like in its validation, doesn't count
+func getVariableReferences(body *hclsyntax.Body) map[string]bool {
+	names := make(map[string]bool)
+	addReferences := func(expr hclsyntax.Expression, self string) {
+		for _, traversal := range expr.Variables() {
+			if name := getVariableName(traversal); name != "" && name != self {
+				names[name] = true
+			}
+		}
+	}
+
+	for _, attr := range body.Attributes {
+		addReferences(attr.Expr, "")
+	}
+	for _, bl := range body.Blocks {
+		self := ""
+		if bl.Type == "variable" && len(bl.Labels) == 1 {
+			self = bl.Labels[0]
+		}
+		walkAttributes(bl.Body, nil, func(attr *hclsyntax.Attribute, address hclAddress) {
+			addReferences(attr.Expr, self)
+		})
+	}
+
+	return names
+}
+
+// getVariableName returns the name of the variable, when the traversal is a reference like var.<name>
+func getVariableName(traversal hcl.Traversal) string {
+	if traversal.RootName() != "var" || len(traversal) < 2 {
+		return ""
+	}
+	if attr, ok := traversal[1].(hcl.TraverseAttr); ok {
+		return attr.Name
+	}
+	return ""
+}
+
+// getLocalModuleCalls returns the module calls with a local source, that call the module in the given dir
+func getLocalModuleCalls(moduleCalls []module, dir string) ([]module, error) {
+	var result []module
+	for _, mod := range moduleCalls {
+		if !isLocalModuleSource(mod.source()) {
+			continue
+		}
+
+		moduleDir, err := resolveModuleDir(mod)
+		if err != nil {
+			return nil, err
+		}
+		if moduleDir != "" && path.Clean(moduleDir) == path.Clean(dir) {
+			result = append(result, mod)
+		}
+	}
+	return result, nil
+}
+
+// getAssigningModuleCalls returns the module calls that assign the variable
+func getAssigningModuleCalls(moduleCalls []module, name string) []module {
+	var result []module
+	for _, mod := range moduleCalls {
+		if _, exists := mod.bl.Body.Attributes[name]; exists {
+			result = append(result, mod)
+		}
+	}
+	return result
+}
+
+// isFixable returns whether the variable can be removed, along with its assignments
+func (v unusedVariable) isFixable() bool {
+	for _, mod := range v.callers {
+		if mod.isInstalled() {
+			// installed modules are overwritten by `terraform init`, so their assignments cannot be removed
+			return false
+		}
+	}
+	return true
+}
+
+// FIX
+
+func performUnusedVariablesFix(tfFiles []string) error {
+	report, err := checkForUnusedVariables(tfFiles)
+	if err != nil {
+		return err
+	}
+
+	for _, unusedVars := range report {
+		for _, v := range unusedVars {
+			if !v.isFixable() {
+				continue
+			}
+			if err = removeUnusedVariable(v); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func removeUnusedVariable(v unusedVariable) error {
+	err := patchFile(v.bl.Range().Filename, func(hclFile *hclwrite.File) (*hclwrite.File, error) {
+		if bl := hclFile.Body().FirstMatchingBlock("variable", v.bl.Labels); bl != nil {
+			removeBlock(hclFile.Body(), bl)
+		}
+		return hclFile, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, mod := range v.callers {
+		err = patchFile(mod.filename(), func(hclFile *hclwrite.File) (*hclwrite.File, error) {
+			if moduleBlock := getModuleBlockForWrite(hclFile, mod); moduleBlock != nil {
+				moduleBlock.Body().RemoveAttribute(v.name())
+			}
+			return hclFile, nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
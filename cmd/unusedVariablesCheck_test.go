@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+func TestGetVariableReferences(t *testing.T) {
+	src := `
+variable "self" {
+  validation {
+    condition     = length(var.self) > 0 && var.other != ""
+    error_message = "invalid"
+  }
+}
+
+resource "aws_instance" "x" {
+  ami = var.ami
+  dynamic "ebs_block_device" {
+    for_each = var.disks
+    content {
+      tags = { for k, v in var.tags : k => "${v}-${var.suffix}" }
+    }
+  }
+}
+
+locals {
+  names = [for var in ["a"] : var]
+}
+`
+	hclFile, diags := hclsyntax.ParseConfig([]byte(src), "dummy.tf", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		t.Fatalf("source is not valid HCL: diagnostics: %v", diags)
+	}
+
+	result := getVariableReferences(hclFile.Body.(*hclsyntax.Body))
+	expected := map[string]bool{"other": true, "ami": true, "disks": true, "tags": true, "suffix": true}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("getVariableReferences() = %v; want %v", result, expected)
+	}
+}
+
+func TestUnusedVariablesFix(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"main.tf": `module "a" {
+  source = "./modules/m"
+  used   = "x"
+  unused = "y"
+}
+
+module "b" {
+  source = "./modules/m"
+  used   = "z"
+}
+`,
+		"modules/m/main.tf": `variable "used" {}
+
+variable "unused" {
+  default = "y"
+}
+
+output "used" {
+  value = var.used
+}
+`,
+	})
+	chdirForTest(t, dir)
+	tfFiles := []string{"main.tf", "modules/m/main.tf"}
+
+	violations, err := checkUnusedVariables(tfFiles)
+	if err != nil {
+		t.Fatalf("checkUnusedVariables() error = %v", err)
+	}
+
+	// module "b" doesn't assign the variable, so it is not changed by the fix
+	if len(violations) != 1 || violations[0].note != "the fix also removes the assignment from module 'a'" {
+		t.Fatalf("checkUnusedVariables() = %+v; want only the variable, with the assignment of module 'a'", violations)
+	}
+
+	dryRun = true
+	defer func() { dryRun = false }()
+
+	if err = performUnusedVariablesFix(tfFiles); err != nil {
+		t.Fatalf("performUnusedVariablesFix() error = %v", err)
+	}
+
+	expected := map[string]string{
+		"main.tf": `module "a" {
+  source = "./modules/m"
+  used   = "x"
+}
+
+module "b" {
+  source = "./modules/m"
+  used   = "z"
+}
+`,
+		"modules/m/main.tf": `variable "used" {}
+
+output "used" {
+  value = var.used
+}
+`,
+	}
+	for filename, content := range expected {
+		if string(pendingFiles[filename]) != content {
+			t.Errorf("performUnusedVariablesFix() resulted in %v:\n%s\nwant:\n%s", filename, pendingFiles[filename], content)
+		}
+	}
+}
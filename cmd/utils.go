@@ -6,13 +6,35 @@ import (
 	"io/fs"
 	"os"
 	"path"
+	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclparse"
 	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
 )
 
 func ensureTargetDir() error {
+	if configFile != "" {
+		// the config file is relative to the dir the command was started from
+		absConfigFile, err := filepath.Abs(configFile)
+		if err != nil {
+			return err
+		}
+		configFile = absConfigFile
+	}
+
+	// the var files are relative to the dir the command was started from as well
+	for i, f := range varFiles {
+		absVarFile, err := filepath.Abs(f)
+		if err != nil {
+			return err
+		}
+		varFiles[i] = absVarFile
+	}
+
 	if targetDir != "" {
 		err := os.Chdir(targetDir)
 		if err != nil {
@@ -24,8 +46,15 @@ func ensureTargetDir() error {
 }
 
 func getTerraformFiles() ([]string, error) {
-	dir := os.DirFS(".")
-	matches, err := fs.Glob(dir, "*.tf")
+	excludes := parseIgnorePatterns(".", excludePatterns)
+
+	var matches []string
+	var err error
+	if recursive {
+		matches, err = walkTerraformFiles(excludes)
+	} else {
+		matches, err = globTerraformFiles(excludes)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -37,8 +66,79 @@ func getTerraformFiles() ([]string, error) {
 	return matches, nil
 }
 
+func globTerraformFiles(excludes ignoreRules) ([]string, error) {
+	matches, err := fs.Glob(os.DirFS("."), "*.tf")
+	if err != nil {
+		return nil, err
+	}
+
+	var result []string
+	for _, m := range matches {
+		if !excludes.ignores(m, false) {
+			result = append(result, m)
+		}
+	}
+
+	return result, nil
+}
+
+// walkTerraformFiles finds the TF files in the whole directory tree, skipping the ones that are ignored by git
+func walkTerraformFiles(excludes ignoreRules) ([]string, error) {
+	var gitignores ignoreRules
+	var matches []string
+
+	err := filepath.WalkDir(".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		p = filepath.ToSlash(p)
+		isIgnored := excludes.ignores(p, d.IsDir()) || gitignores.ignores(p, d.IsDir())
+
+		if d.IsDir() {
+			if p != "." && (d.Name() == ".terraform" || d.Name() == ".git" || isIgnored) {
+				return filepath.SkipDir
+			}
+
+			rules, err := readGitignore(p)
+			if err != nil {
+				return err
+			}
+			gitignores = append(gitignores, rules...)
+
+			return nil
+		}
+
+		if path.Ext(p) == ".tf" && !isIgnored {
+			matches = append(matches, p)
+		}
+
+		return nil
+	})
+
+	return matches, err
+}
+
+// getDirTerraformFiles returns the TF files in the given dir, which together form a single terraform module
+func getDirTerraformFiles(dir string) ([]string, error) {
+	matches, err := fs.Glob(os.DirFS(dir), "*.tf")
+	if err != nil {
+		return nil, err
+	}
+
+	var filenames []string
+	for _, m := range matches {
+		filenames = append(filenames, path.Join(dir, m))
+	}
+	return filenames, nil
+}
+
 type module struct {
 	bl *hclsyntax.Block
+
+	// parentKey is the key of the module that calls this module, or empty when called from the root module
+	parentKey     string
+	rootModuleDir string
 }
 
 type variableDefinition struct {
@@ -70,22 +170,141 @@ func readModules(filename string) ([]module, error) {
 
 	var modules []module
 	for _, bl := range moduleBlocks {
-		modules = append(modules, module{bl})
+		modules = append(modules, module{bl, "", path.Dir(filename)})
 	}
 
 	return modules, nil
 }
 
+// getModuleCallTree returns the modules that are called from the root modules in the files, followed by the modules
+// they call themselves. A dir of the files is only a root module when none of the local module calls in the files calls
+// it, as the files of local modules are part of the files as well when walking the dirs recursively.
+func getModuleCallTree(filenames []string) ([]module, error) {
+	referencedModules, err := getReferencedModules(filenames)
+	if err != nil {
+		return nil, err
+	}
+
+	var localModules []module
+	for _, mod := range referencedModules {
+		if isLocalModuleSource(mod.source()) {
+			localModules = append(localModules, mod)
+		}
+	}
+
+	rootDirs := make(map[string]bool)
+	var rootFilenames []string
+	for _, f := range filenames {
+		dir := path.Dir(f)
+		isRoot, checked := rootDirs[dir]
+		if !checked {
+			if isRoot, err = isRootModuleDir(dir, localModules); err != nil {
+				return nil, err
+			}
+			rootDirs[dir] = isRoot
+		}
+		if isRoot {
+			rootFilenames = append(rootFilenames, f)
+		}
+	}
+
+	var allModules []module
+	for _, mod := range referencedModules {
+		if !rootDirs[mod.rootDir()] {
+			// reached through the module calls of its callers instead
+			continue
+		}
+
+		nestedModules, err := getNestedModules(mod, rootFilenames, nil)
+		if err != nil {
+			return nil, err
+		}
+		allModules = append(allModules, mod)
+		allModules = append(allModules, nestedModules...)
+	}
+
+	return uniqueModules(allModules)
+}
+
+// uniqueModules drops the module calls that are visited before, as the module calls of a local module are visited once
+// for every module that calls it, while these are the same module calls in the same dir
+func uniqueModules(modules []module) ([]module, error) {
+	seen := make(map[string]bool)
+	var unique []module
+	for _, mod := range modules {
+		moduleDir, err := resolveModuleDir(mod)
+		if err != nil {
+			return nil, err
+		}
+
+		key := moduleDir + ":" + mod.bl.Range().String()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, mod)
+	}
+
+	return unique, nil
+}
+
+func getNestedModules(parent module, rootFilenames []string, visitedDirs []string) ([]module, error) {
+	moduleDir, err := resolveModuleDir(parent)
+	if err != nil || moduleDir == "" {
+		return nil, err
+	}
+
+	if contains(visitedDirs, moduleDir) {
+		// module calls itself (indirectly), which terraform doesn't allow either
+		return nil, nil
+	}
+	visitedDirs = append(visitedDirs, moduleDir)
+
+	filenames, err := getDirTerraformFiles(moduleDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var allModules []module
+	for _, filename := range filenames {
+		if contains(rootFilenames, filename) {
+			// already processed as module calls from a root module
+			continue
+		}
+
+		moduleBlocks, err := getBlocksFromFile(filename, "module")
+		if err != nil {
+			return nil, err
+		}
+
+		for _, bl := range moduleBlocks {
+			mod := module{bl, parent.key(), parent.rootDir()}
+			nestedModules, err := getNestedModules(mod, rootFilenames, visitedDirs)
+			if err != nil {
+				return nil, err
+			}
+			allModules = append(allModules, mod)
+			allModules = append(allModules, nestedModules...)
+		}
+	}
+
+	return allModules, nil
+}
+
 func getModuleVariables(mod module) ([]variableDefinition, error) {
-	moduleDir := path.Join(".terraform/modules/", mod.name())
-	matches, err := fs.Glob(os.DirFS(moduleDir), "*.tf")
+	moduleDir, err := resolveModuleDir(mod)
+	if err != nil || moduleDir == "" {
+		return nil, err
+	}
+
+	filenames, err := getDirTerraformFiles(moduleDir)
 	if err != nil {
 		return nil, err
 	}
 
 	var allVariables []variableDefinition
-	for _, m := range matches {
-		vars, err := readVariables(path.Join(moduleDir, m))
+	for _, filename := range filenames {
+		vars, err := readVariables(filename)
 		if err != nil {
 			return nil, err
 		}
@@ -95,6 +314,66 @@ func getModuleVariables(mod module) ([]variableDefinition, error) {
 	return allVariables, nil
 }
 
+// moduleDeclarations holds the type constraints of the variables and the expressions of the locals of a module, to
+// determine what references to them evaluate to
+type moduleDeclarations struct {
+	variableTypes map[string]hclsyntax.Expression
+	locals        map[string]hclsyntax.Expression
+}
+
+func getModuleDeclarations(dir string) (moduleDeclarations, error) {
+	decls := moduleDeclarations{
+		variableTypes: make(map[string]hclsyntax.Expression),
+		locals:        make(map[string]hclsyntax.Expression),
+	}
+
+	filenames, err := getDirTerraformFiles(dir)
+	if err != nil {
+		return decls, err
+	}
+
+	for _, f := range filenames {
+		vars, err := readVariables(f)
+		if err != nil {
+			return decls, err
+		}
+		for _, v := range vars {
+			if typeAttr := getAttribute(v.bl.Body, "type"); typeAttr != nil {
+				decls.variableTypes[v.name()] = typeAttr.Expr
+			}
+		}
+
+		localsBlocks, err := getBlocksFromFile(f, "locals")
+		if err != nil {
+			return decls, err
+		}
+		for _, bl := range localsBlocks {
+			for name, attr := range bl.Body.Attributes {
+				decls.locals[name] = attr.Expr
+			}
+		}
+	}
+
+	return decls, nil
+}
+
+// hasSkippedFiles returns whether any of the files in the module dir cannot be parsed, in which case the variables of
+// the module are only partially known
+func hasSkippedFiles(moduleDir string) (bool, error) {
+	filenames, err := getDirTerraformFiles(moduleDir)
+	if err != nil {
+		return false, err
+	}
+
+	skipped := false
+	for _, filename := range filenames {
+		if parseHclFileOrSkip(filename) == nil {
+			skipped = true
+		}
+	}
+	return skipped, nil
+}
+
 func (mod module) name() string {
 	return blockName(mod.bl)
 }
@@ -132,6 +411,62 @@ func (mod module) filename() string {
 	return mod.bl.Range().Filename
 }
 
+// key returns the key of the module in the module manifest, e.g. "vpc.subnets"
+func (mod module) key() string {
+	if mod.parentKey == "" {
+		return mod.name()
+	}
+	return mod.parentKey + "." + mod.name()
+}
+
+// address returns the address of the module like terraform uses it, e.g. "module.vpc.module.subnets"
+func (mod module) address() string {
+	return mod.hclAddress("").string()
+}
+
+// hclAddress returns the address of the given attribute of the module
+func (mod module) hclAddress(attrName string) hclAddress {
+	var blocks []hclBlockId
+	for _, name := range strings.Split(mod.key(), ".") {
+		blocks = append(blocks, hclBlockId{typeName: "module", labels: []string{name}})
+	}
+	return hclAddress{blocks, attrName}
+}
+
+// rootDir returns the directory of the root module, that contains the .terraform dir
+func (mod module) rootDir() string {
+	return mod.rootModuleDir
+}
+
+// isInstalled returns whether the module call is in a module that was installed by `terraform init`, and therefore
+// should not be changed
+func (mod module) isInstalled() bool {
+	return strings.Contains("/"+mod.filename(), "/.terraform/")
+}
+
+// source returns the source of the module, or an empty string when it is not a literal string
+func (mod module) source() string {
+	return getStringAttribute(mod.bl.Body, "source")
+}
+
+// displayName returns the name of a module called from the root module, and the address for nested modules
+func (mod module) displayName() string {
+	if mod.parentKey == "" {
+		return mod.name()
+	}
+	return mod.address()
+}
+
+// description returns the name of the module for reporting, including its directory when not in the working dir
+func (mod module) description() string {
+	name := mod.displayName()
+	dir := path.Dir(mod.filename())
+	if dir == "." {
+		return fmt.Sprintf("module '%v'", name)
+	}
+	return fmt.Sprintf("module '%v' in '%v'", name, dir)
+}
+
 
 func blockName(bl *hclsyntax.Block) string {
 	return bl.Labels[0]
@@ -141,6 +476,56 @@ func location(r hcl.Range) string {
 	return fmt.Sprintf("%v:%v", r.Filename, r.Start.Line)
 }
 
+// readFile reads the file, taking into account the changes that are pending in dry-run mode
+func readFile(filename string) ([]byte, error) {
+	if content, exists := pendingFiles[filename]; exists {
+		return content, nil
+	}
+	return os.ReadFile(filename)
+}
+
+// skippedFiles holds the files that cannot be parsed, so that they are reported only once
+var skippedFiles = make(map[string]bool)
+
+// parseHclFileOrSkip parses the file, or reports why it cannot be parsed and returns nil, so that the file can be
+// skipped while the other files are still processed
+func parseHclFileOrSkip(filename string) *hcl.File {
+	input, err := readFile(filename)
+	if err != nil {
+		if !skippedFiles[filename] {
+			reportWarning(nil, fmt.Sprintf("skipping file '%v'", filename), err.Error())
+		}
+		skippedFiles[filename] = true
+		return nil
+	}
+
+	hclFile, diags := hclparse.NewParser().ParseHCL(input, filename)
+	if diags.HasErrors() {
+		if skippedFiles[filename] {
+			return nil
+		}
+		skippedFiles[filename] = true
+
+		for _, diag := range diags {
+			if diag.Severity == hcl.DiagError {
+				reportWarning(diag.Subject, fmt.Sprintf("skipping file '%v': %v", filename, diag.Summary), diag.Detail)
+			}
+		}
+		return nil
+	}
+
+	return hclFile
+}
+
+// sourceText returns the source text of the given range, read from its file
+func sourceText(r hcl.Range) string {
+	input, _ := readFile(r.Filename)
+	if r.End.Byte > len(input) {
+		return ""
+	}
+	return string(r.SliceBytes(input))
+}
+
 func getAttribute(body *hclsyntax.Body, attrName string) *hclsyntax.Attribute {
 	// TODO: Convert this to casting body.Attributes to map[string]*Attribute
 	for _, a := range body.Attributes {
@@ -151,6 +536,19 @@ func getAttribute(body *hclsyntax.Body, attrName string) *hclsyntax.Attribute {
 	return nil
 }
 
+func getStringAttribute(body *hclsyntax.Body, attrName string) string {
+	attr := getAttribute(body, attrName)
+	if attr == nil {
+		return ""
+	}
+
+	val, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() || !val.IsKnown() || val.IsNull() || val.Type() != cty.String {
+		return ""
+	}
+	return val.AsString()
+}
+
 func readVariables(filename string) ([]variableDefinition, error) {
 	variableBlocks, err := getBlocksFromFile(filename, "variable")
 	if err != nil {
@@ -166,9 +564,9 @@ func readVariables(filename string) ([]variableDefinition, error) {
 }
 
 func getBlocksFromFile(filename, blockName string) ([]*hclsyntax.Block, error) {
-	hclFile, diags := hclparse.NewParser().ParseHCLFile(filename)
-	if diags.HasErrors() {
-		return nil, errors.New("failed to parse TF file: " + diags.Error())
+	hclFile := parseHclFileOrSkip(filename)
+	if hclFile == nil {
+		return nil, nil
 	}
 
 	hclBody := hclFile.Body.(*hclsyntax.Body)
@@ -194,33 +592,78 @@ func getVariableAssignments(module module) map[string]expression {
 	return newMap
 }
 
-func equalToVariableDefinition(assignExpr expression, varDefinition variableDefinition) bool {
+// equalToVariableDefinition returns whether the assigned value, evaluated with the context of the calling module, is
+// known and equal to the default value of the variable, after both are converted to the type of the variable
+func equalToVariableDefinition(assignExpr expression, ctx *hcl.EvalContext, varDefinition variableDefinition) bool {
 	defaultValue := varDefinition.defaultValue()
 
 	if defaultValue == nil {
 		return false
 	}
 
-	return equals(assignExpr, *defaultValue)
+	valA, ok := knownValue(assignExpr, ctx, varDefinition)
+	if !ok {
+		return false
+	}
+	valB, ok := knownValue(*defaultValue, nil, varDefinition)
+	if !ok {
+		return false
+	}
+
+	return valA.RawEquals(valB)
 }
 
-func equals(a expression, b expression) bool {
-	valA, _ := a.attr.Expr.Value(&hcl.EvalContext{})
-	valB, _ := b.attr.Expr.Value(&hcl.EvalContext{})
+// knownValue returns the value of the expression converted to the type of the variable, when it is fully known
+func knownValue(expr expression, ctx *hcl.EvalContext, varDefinition variableDefinition) (cty.Value, bool) {
+	val, diags := expr.attr.Expr.Value(ctx)
+	if diags.HasErrors() || !val.IsWhollyKnown() {
+		return cty.NilVal, false
+	}
 
-	return valA.RawEquals(valB)
+	val, err := convertToVariableType(varDefinition, val)
+	if err != nil {
+		return cty.NilVal, false
+	}
+	return val, true
 }
 
-func readHclTokens(filename string) (hclsyntax.Tokens, error) {
-	input, _ := os.ReadFile(filename)
-	tokens, diags := hclsyntax.LexConfig(input, filename, hcl.InitialPos)
-	if diags.HasErrors() {
-		return nil, errors.New("failed to parse TF file: " + diags.Error())
+// walkAttributes calls visit for every attribute in the body, including the ones in nested blocks
+func walkAttributes(body *hclsyntax.Body, blocks []hclBlockId, visit func(attr *hclsyntax.Attribute, address hclAddress)) {
+	var attrs []*hclsyntax.Attribute
+	for _, attr := range body.Attributes {
+		attrs = append(attrs, attr)
+	}
+	sort.Slice(attrs, func(i, j int) bool {
+		return attrs[i].SrcRange.Start.Byte < attrs[j].SrcRange.Start.Byte
+	})
+
+	for _, attr := range attrs {
+		visit(attr, hclAddress{blocks, attr.Name})
 	}
 
-	return tokens, nil
+	var visited []hclBlockId
+	for _, bl := range body.Blocks {
+		blId := hclBlockId{typeName: bl.Type, labels: bl.Labels}
+		for _, prev := range visited {
+			if prev.typeName == blId.typeName && equalsLabels(prev.labels, blId.labels) {
+				blId.index++
+			}
+		}
+		visited = append(visited, blId)
+
+		blIds := append(blocks[:len(blocks):len(blocks)], blId)
+		walkAttributes(bl.Body, blIds, visit)
+	}
 }
 
-func isTokenText(token hclsyntax.Token, text string) bool {
-	return string(token.Bytes) == text
+// findFunctionCalls returns all calls to the function with the given name in the expression
+func findFunctionCalls(expr hclsyntax.Expression, name string) []*hclsyntax.FunctionCallExpr {
+	var calls []*hclsyntax.FunctionCallExpr
+	hclsyntax.VisitAll(expr, func(node hclsyntax.Node) hcl.Diagnostics {
+		if call, ok := node.(*hclsyntax.FunctionCallExpr); ok && call.Name == name {
+			calls = append(calls, call)
+		}
+		return nil
+	})
+	return calls
 }
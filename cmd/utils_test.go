@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// writeTestFiles writes the files, with their paths relative to the dir, creating the dirs they are in
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		filename := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+			t.Fatalf("failed to create dir for %v: %v", name, err)
+		}
+		if err := os.WriteFile(filename, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write %v: %v", name, err)
+		}
+	}
+}
+
+// chdirForTest changes the working dir to the dir for the duration of the test, with the caches of the files and
+// modules in the previous dir cleared, as those hold relative paths
+func chdirForTest(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working dir: %v", err)
+	}
+	if err = os.Chdir(dir); err != nil {
+		t.Fatalf("failed to change working dir: %v", err)
+	}
+	resetCaches()
+
+	t.Cleanup(func() {
+		if err := os.Chdir(wd); err != nil {
+			t.Fatalf("failed to restore working dir: %v", err)
+		}
+		resetCaches()
+	})
+}
+
+func resetCaches() {
+	skippedFiles = make(map[string]bool)
+	pendingFiles = make(map[string][]byte)
+	resolvedModuleDirs = make(map[module]string)
+	moduleManifests = make(map[string]*moduleManifest)
+	moduleEvalContexts = make(map[string]*hcl.EvalContext)
+}
+
+// captureDiagnostics collects the diagnostics that are reported during the test
+func captureDiagnostics(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	diagnosticsOutput = &buf
+	t.Cleanup(func() {
+		diagnosticsOutput = os.Stderr
+	})
+	return &buf
+}
+
+// sharedModuleFiles is a root module that calls two modules, which both call the same shared module, that calls a
+// module itself
+var sharedModuleFiles = map[string]string{
+	"main.tf":                "module \"a\" {\n  source = \"./modules/a\"\n}\n\nmodule \"b\" {\n  source = \"./modules/b\"\n}\n",
+	"modules/a/main.tf":      "module \"shared\" {\n  source = \"../shared\"\n}\n",
+	"modules/b/main.tf":      "module \"shared\" {\n  source = \"../shared\"\n}\n",
+	"modules/shared/main.tf": "module \"leaf\" {\n  source = \"../leaf\"\n  size   = 1\n}\n",
+	"modules/leaf/main.tf":   "variable \"size\" {\n  type    = number\n  default = 1\n}\n",
+}
+
+func TestGetModuleCallTreeWithSharedModule(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, sharedModuleFiles)
+	chdirForTest(t, dir)
+
+	moduleCalls, err := getModuleCallTree([]string{"main.tf"})
+	if err != nil {
+		t.Fatalf("getModuleCallTree() error = %v", err)
+	}
+
+	var result []string
+	for _, mod := range moduleCalls {
+		result = append(result, mod.filename()+":"+mod.name())
+	}
+
+	// the call of the leaf module is visited through both callers of the shared module, but is the same module call
+	expected := []string{"main.tf:a", "modules/a/main.tf:shared", "modules/shared/main.tf:leaf", "main.tf:b", "modules/b/main.tf:shared"}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("getModuleCallTree() = %v; want %v", result, expected)
+	}
+}
+
+func TestWalkTerraformFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"main.tf":                          "",
+		"dev.local.tf":                     "",
+		".gitignore":                       "*.local.tf\ngenerated/\n",
+		"generated/main.tf":                "",
+		"stacks/.gitignore":                "!keep.local.tf\ntmp.tf\n",
+		"stacks/keep.local.tf":             "",
+		"stacks/tmp.tf":                    "",
+		"stacks/a/main.tf":                 "",
+		"stacks/a/tmp.tf":                  "",
+		"stacks/a/other.local.tf":          "",
+		"other/.gitignore":                 "main.tf\n",
+		"other/main.tf":                    "",
+		"other/vars.tf":                    "",
+		".terraform/modules/vpc/main.tf":   "",
+		"stacks/a/.terraform/modules/m.tf": "",
+		".git/hooks/main.tf":               "",
+		"README.md":                        "",
+	})
+	chdirForTest(t, dir)
+
+	testCases := []struct {
+		name     string
+		excludes []string
+		expected []string
+	}{
+		{
+			name: "gitignore files",
+			// the rules of a .gitignore apply to its dir and below, where a later rule can negate an earlier one
+			expected: []string{"main.tf", "other/vars.tf", "stacks/a/main.tf", "stacks/keep.local.tf"},
+		},
+		{
+			name:     "excludes",
+			excludes: []string{"stacks/a", "*.local.tf"},
+			expected: []string{"main.tf", "other/vars.tf"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := walkTerraformFiles(parseIgnorePatterns(".", tc.excludes))
+			if err != nil {
+				t.Fatalf("walkTerraformFiles() error = %v", err)
+			}
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Errorf("walkTerraformFiles() = %v; want %v", result, tc.expected)
+			}
+		})
+	}
+}
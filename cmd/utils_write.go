@@ -4,13 +4,21 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 
 	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/hashicorp/hcl/v2/hclwrite"
 )
 
+var dryRun bool
+
+// pendingFiles holds the patched content of the files in dry-run mode, instead of writing them to disk
+var pendingFiles = make(map[string][]byte)
+
 func patchFile(filename string, patch func(hclFile *hclwrite.File) (*hclwrite.File, error)) error {
-	input, _ := os.ReadFile(filename)
+	input, _ := readFile(filename)
 	hclFile, diags := hclwrite.ParseConfig(input, filename, hcl.Pos{Line: 1, Column: 1})
 
 	if diags.HasErrors() {
@@ -22,6 +30,11 @@ func patchFile(filename string, patch func(hclFile *hclwrite.File) (*hclwrite.Fi
 		return err
 	}
 
+	if dryRun {
+		pendingFiles[filename] = newHclFile.Bytes()
+		return nil
+	}
+
 	if err = os.WriteFile(filename, newHclFile.Bytes(), os.ModePerm); err != nil {
 		return fmt.Errorf("failed to write file: %s", err)
 	}
@@ -29,6 +42,43 @@ func patchFile(filename string, patch func(hclFile *hclwrite.File) (*hclwrite.Fi
 	return nil
 }
 
+// removeBlock removes the block from the body, along with a blank line next to it when it is surrounded by blank lines,
+// so that no double blank line is left behind
+func removeBlock(body *hclwrite.Body, bl *hclwrite.Block) {
+	tokens := body.BuildTokens(nil)
+	blockTokens := bl.BuildTokens(nil)
+	body.RemoveBlock(bl)
+
+	start := -1
+	for i, token := range tokens {
+		if len(blockTokens) > 0 && token == blockTokens[0] {
+			start = i
+			break
+		}
+	}
+	if start == -1 {
+		return
+	}
+	end := start + len(blockTokens)
+
+	blankBefore := start == 0 || (isNewlineToken(tokens[start-1]) && (start == 1 || isNewlineToken(tokens[start-2])))
+	if !blankBefore {
+		return
+	}
+
+	// the tokens of a body cannot be removed individually, so the newline is emptied instead
+	switch {
+	case end < len(tokens) && isNewlineToken(tokens[end]):
+		tokens[end].Bytes = nil
+	case start > 0 && (end == len(tokens) || tokens[end].Type == hclsyntax.TokenEOF):
+		tokens[start-1].Bytes = nil
+	}
+}
+
+func isNewlineToken(token *hclwrite.Token) bool {
+	return token.Type == hclsyntax.TokenNewline
+}
+
 func getModuleBlockForWrite(hclFile *hclwrite.File, mod module) *hclwrite.Block {
 	for _, bl := range hclFile.Body().Blocks() {
 		if bl.Type() == "module" && bl.Labels()[0] == mod.name() {
@@ -37,3 +87,88 @@ func getModuleBlockForWrite(hclFile *hclwrite.File, mod module) *hclwrite.Block
 	}
 	return nil
 }
+
+// exprReplacement replaces the source text at rng, which is part of the expression of the attribute at address
+type exprReplacement struct {
+	address   hclAddress
+	exprRange hcl.Range // range of the whole expression of the attribute
+	rng       hcl.Range
+	text      string
+}
+
+// replaceInAttributes applies the replacements to the file, where a replacement that is nested in another one is
+// skipped, as it is replaced as a whole already. An attribute for which the replacements cannot be applied, is
+// reported and left untouched.
+func replaceInAttributes(filename string, replacements []exprReplacement) error {
+	input, err := readFile(filename)
+	if err != nil {
+		return err
+	}
+
+	var addresses []string
+	byAttribute := make(map[string][]exprReplacement)
+	for _, r := range replacements {
+		key := r.address.string()
+		if _, exists := byAttribute[key]; !exists {
+			addresses = append(addresses, key)
+		}
+		byAttribute[key] = append(byAttribute[key], r)
+	}
+
+	return patchFile(filename, func(hclFile *hclwrite.File) (*hclwrite.File, error) {
+		for _, key := range addresses {
+			attrReplacements := byAttribute[key]
+			exprRange := attrReplacements[0].exprRange
+			newExpr := spliceReplacements(input, exprRange, attrReplacements)
+
+			tokens, err := parseExpressionTokens(newExpr)
+			if err != nil {
+				reportWarning(&exprRange, fmt.Sprintf("cannot fix attribute '%v'", key), err.Error())
+				continue
+			}
+
+			body, attrName := getAttributeForWrite(hclFile, attrReplacements[0].address)
+			if body == nil || body.GetAttribute(attrName) == nil {
+				reportWarning(&exprRange, fmt.Sprintf("cannot fix attribute '%v'", key), "the attribute cannot be found")
+				continue
+			}
+			body.SetAttributeRaw(attrName, tokens)
+		}
+		return hclFile, nil
+	})
+}
+
+func spliceReplacements(input []byte, exprRange hcl.Range, replacements []exprReplacement) string {
+	sort.SliceStable(replacements, func(i, j int) bool {
+		return replacements[i].rng.Start.Byte < replacements[j].rng.Start.Byte
+	})
+
+	var sb strings.Builder
+	pos := exprRange.Start.Byte
+	for _, r := range replacements {
+		if r.rng.Start.Byte < pos {
+			// nested in the previous replacement
+			continue
+		}
+		sb.Write(input[pos:r.rng.Start.Byte])
+		sb.WriteString(r.text)
+		pos = r.rng.End.Byte
+	}
+	sb.Write(input[pos:exprRange.End.Byte])
+
+	return sb.String()
+}
+
+// parseExpressionTokens parses the source text of an expression into tokens for writing
+func parseExpressionTokens(expr string) (hclwrite.Tokens, error) {
+	hclFile, diags := hclwrite.ParseConfig([]byte("expr = "+expr+"\n"), "", hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to parse replacement '%v': %s", expr, diags.Error())
+	}
+
+	tokens := hclFile.Body().GetAttribute("expr").Expr().BuildTokens(nil)
+	if len(tokens) > 0 {
+		tokens[0].SpacesBefore = 0
+	}
+	return tokens, nil
+}